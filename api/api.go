@@ -0,0 +1,176 @@
+// Package api implements a client for the Slack Web API.
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultURL is the base URL for all Slack Web API methods.
+const DefaultURL = "https://slack.com/api/"
+
+// DefaultTimeout bounds each request made by a Client created without
+// WithHTTPClient, including reading the response. Requests that time out
+// count as failures for the client's Breaker.
+const DefaultTimeout = 30 * time.Second
+
+// Client is a Slack Web API client. Clients are safe for concurrent use.
+type Client struct {
+	token    string
+	url      string
+	http     *http.Client
+	breaker  *Breaker
+	throttle *Throttle
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// NewClient creates a Web API client that authenticates with the provided
+// token. Its calls are paced by a zero Throttle unless WithThrottle sets
+// another.
+func NewClient(token string, opts ...Option) *Client {
+	c := &Client{
+		token:    token,
+		url:      DefaultURL,
+		http:     &http.Client{Timeout: DefaultTimeout},
+		throttle: &Throttle{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// WithHTTPClient sets the HTTP client used to call Slack. The default has
+// a timeout of DefaultTimeout; set a client with a longer one to download
+// large files. A client without a timeout lets hung requests block the
+// caller indefinitely.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		c.http = client
+	}
+}
+
+// WithBreaker makes the client fail fast with ErrBreakerOpen while Slack
+// is failing, as decided by the breaker.
+func WithBreaker(b *Breaker) Option {
+	return func(c *Client) {
+		c.breaker = b
+	}
+}
+
+// WithThrottle makes the client pace its calls to stay under each
+// method's rate limit tier, as decided by the throttle. Share a throttle
+// between clients using the same token so they are paced together. A nil
+// throttle turns pacing off.
+func WithThrottle(t *Throttle) Option {
+	return func(c *Client) {
+		c.throttle = t
+	}
+}
+
+// Response contains the fields common to all Web API responses.
+type Response struct {
+	// Ok is true if the method call succeeded
+	Ok bool `json:"ok"`
+	// Error contains an error code if Ok is false e.g. "channel_not_found"
+	Error string `json:"error,omitempty"`
+	// Warning contains a comma separated list of warnings if the call
+	// succeeded but something about it should be fixed.
+	Warning string `json:"warning,omitempty"`
+	// Metadata contains pagination information for paginated methods.
+	Metadata ResponseMetadata `json:"response_metadata,omitempty"`
+}
+
+// ResponseMetadata contains pagination information for paginated methods.
+type ResponseMetadata struct {
+	// NextCursor is the cursor for the next page of results or empty when
+	// there are no more results.
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// Error is returned when a Web API method responds with ok set to false.
+type Error struct {
+	// Method is the Web API method that was called e.g. "conversations.history"
+	Method string
+	// Code is the error code returned by Slack e.g. "channel_not_found"
+	Code string
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	return fmt.Sprintf("slack: %s failed: %s", e.Method, e.Code)
+}
+
+// RateLimitedError is returned when Slack rejects a call because the
+// method's rate limit was exceeded (HTTP status 429).
+type RateLimitedError struct {
+	// Method is the Web API method that was called
+	Method string
+	// RetryAfter is how long Slack asked to wait before calling again
+	RetryAfter time.Duration
+}
+
+// Error implements the error interface.
+func (e *RateLimitedError) Error() string {
+	return fmt.Sprintf("slack: %s rate limited, retry after %s", e.Method, e.RetryAfter)
+}
+
+// Call invokes the named Web API method with the provided arguments and
+// decodes the JSON response into v. An *Error is returned if Slack
+// reports that the call failed, a *RateLimitedError if the call was
+// rejected by rate limiting and ErrBreakerOpen if the client's circuit
+// breaker is open. Calls the breaker lets through wait for the client's
+// throttle, if any; the wait can't be cancelled but is bounded by the
+// method's rate and the Retry-After of the last rejected call.
+func (c *Client) Call(method string, args url.Values, v interface{}) error {
+	req, err := http.NewRequest("POST", c.url+method, strings.NewReader(args.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	resp, err := c.do(method, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		retryAfter, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+		if err != nil || retryAfter < 1 {
+			retryAfter = 1
+		}
+		limited := &RateLimitedError{Method: method, RetryAfter: time.Duration(retryAfter) * time.Second}
+		if c.throttle != nil {
+			c.throttle.limited(limited)
+		}
+		return limited
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("slack: %s returned HTTP status %s", method, resp.Status)
+	}
+
+	var r Response
+	err = json.Unmarshal(body, &r)
+	if err != nil {
+		return err
+	}
+	if !r.Ok {
+		return &Error{Method: method, Code: r.Error}
+	}
+	if v == nil {
+		return nil
+	}
+	return json.Unmarshal(body, v)
+}
@@ -0,0 +1,19 @@
+package api
+
+// connectionsOpenResponse is received from the apps.connections.open API.
+type connectionsOpenResponse struct {
+	Response
+	URL string `json:"url"`
+}
+
+// OpenConnection asks for a Socket Mode websocket URL. The client must be
+// authenticated with an app-level token (xapp-...) that has the
+// connections:write scope. Each URL can be connected to once.
+func (c *Client) OpenConnection() (string, error) {
+	var r connectionsOpenResponse
+	err := c.Call("apps.connections.open", nil, &r)
+	if err != nil {
+		return "", err
+	}
+	return r.URL, nil
+}
@@ -0,0 +1,186 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Defaults used by a Breaker whose fields are not set.
+const (
+	DefaultBreakerThreshold = 5
+	DefaultBreakerCooldown  = 30 * time.Second
+)
+
+// ErrBreakerOpen is returned without calling Slack while a client's circuit
+// breaker is open.
+var ErrBreakerOpen = errors.New("slack: circuit breaker open, not calling the Web API")
+
+// BreakerState is the state of a circuit breaker.
+type BreakerState int
+
+// Circuit breaker states.
+const (
+	// BreakerClosed lets every request through.
+	BreakerClosed BreakerState = iota
+	// BreakerOpen fails every request with ErrBreakerOpen until the
+	// cooldown has passed.
+	BreakerOpen
+	// BreakerHalfOpen lets a single trial request through to find out if
+	// Slack has recovered.
+	BreakerHalfOpen
+)
+
+// String returns the name of the state.
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// Breaker is a circuit breaker that stops a Client calling Slack while it
+// is failing. After Threshold consecutive requests fail with a network
+// error or a 5xx status the breaker opens and requests fail fast with
+// ErrBreakerOpen for the Cooldown period. Then a single trial request is
+// let through: if it succeeds the breaker closes, otherwise it opens for
+// another cooldown. Errors reported by Slack in a successful response,
+// such as channel_not_found, and rate limiting don't count as failures.
+//
+// The zero value is ready to use. A Breaker may be shared by several
+// clients calling the same workspace and is safe for concurrent use.
+type Breaker struct {
+	// Threshold is the number of consecutive failures that open the
+	// breaker. Zero uses DefaultBreakerThreshold.
+	Threshold int
+	// Cooldown is how long the breaker stays open before trying Slack
+	// again. Zero uses DefaultBreakerCooldown.
+	Cooldown time.Duration
+	// OnStateChange, if set, is called whenever the breaker changes state,
+	// e.g. to log or alert on outages. It is called synchronously by the
+	// goroutine making the request so it should not block.
+	OnStateChange func(from, to BreakerState)
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	trial    bool // true while the half-open trial request is running
+}
+
+// State returns the current state of the breaker.
+func (b *Breaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerOpen && time.Since(b.openedAt) >= b.cooldown() {
+		return BreakerHalfOpen
+	}
+	return b.state
+}
+
+// allow reports whether a request may be made, returning ErrBreakerOpen
+// if not. Every allowed request must be followed by a call to done.
+func (b *Breaker) allow() error {
+	b.mu.Lock()
+	from := b.state
+	switch b.state {
+	case BreakerOpen:
+		if time.Since(b.openedAt) < b.cooldown() {
+			b.mu.Unlock()
+			return ErrBreakerOpen
+		}
+		b.state = BreakerHalfOpen
+		b.trial = true
+	case BreakerHalfOpen:
+		if b.trial {
+			b.mu.Unlock()
+			return ErrBreakerOpen
+		}
+		b.trial = true
+	}
+	to := b.state
+	b.mu.Unlock()
+	b.changed(from, to)
+	return nil
+}
+
+// done records the outcome of an allowed request.
+func (b *Breaker) done(failed bool) {
+	b.mu.Lock()
+	from := b.state
+	switch {
+	case !failed:
+		b.failures = 0
+		b.state = BreakerClosed
+	case b.state == BreakerHalfOpen:
+		b.state = BreakerOpen
+		b.openedAt = time.Now()
+	default:
+		b.failures++
+		if b.state == BreakerClosed && b.failures >= b.threshold() {
+			b.state = BreakerOpen
+			b.openedAt = time.Now()
+		}
+	}
+	if from == BreakerHalfOpen {
+		b.trial = false
+	}
+	to := b.state
+	b.mu.Unlock()
+	b.changed(from, to)
+}
+
+// changed calls OnStateChange if the state changed.
+func (b *Breaker) changed(from, to BreakerState) {
+	if from != to && b.OnStateChange != nil {
+		b.OnStateChange(from, to)
+	}
+}
+
+func (b *Breaker) threshold() int {
+	if b.Threshold > 0 {
+		return b.Threshold
+	}
+	return DefaultBreakerThreshold
+}
+
+func (b *Breaker) cooldown() time.Duration {
+	if b.Cooldown > 0 {
+		return b.Cooldown
+	}
+	return DefaultBreakerCooldown
+}
+
+// do sends the request for the method through the client's breaker and
+// throttle, if any. The breaker is checked first so calls fail fast while
+// it is open instead of waiting for the throttle. Requests that aren't Web
+// API calls, such as file transfers, pass an empty method and aren't
+// throttled.
+func (c *Client) do(method string, req *http.Request) (*http.Response, error) {
+	if c.breaker == nil {
+		c.wait(method)
+		return c.http.Do(req)
+	}
+	err := c.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+	c.wait(method)
+	resp, err := c.http.Do(req)
+	c.breaker.done(err != nil || resp.StatusCode >= 500)
+	return resp, err
+}
+
+// wait blocks until the client's throttle, if any, allows the method to be
+// called.
+func (c *Client) wait(method string) {
+	if c.throttle != nil && method != "" {
+		c.throttle.wait(method)
+	}
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// breakerStep is a request made through a breaker, or a wait for its
+// cooldown, and the state expected afterwards.
+type breakerStep struct {
+	op      string // "fail", "ok", "rejected" or "cooldown"
+	want    BreakerState
+	changes int // state changes reported so far
+}
+
+func TestBreakerTransitions(t *testing.T) {
+	const cooldown = 20 * time.Millisecond
+	tests := []struct {
+		name  string
+		steps []breakerStep
+	}{
+		{"successes stay closed", []breakerStep{
+			{"ok", BreakerClosed, 0},
+			{"ok", BreakerClosed, 0},
+		}},
+		{"opens at threshold", []breakerStep{
+			{"fail", BreakerClosed, 0},
+			{"fail", BreakerOpen, 1},
+			{"rejected", BreakerOpen, 1},
+		}},
+		{"success resets failures", []breakerStep{
+			{"fail", BreakerClosed, 0},
+			{"ok", BreakerClosed, 0},
+			{"fail", BreakerClosed, 0},
+		}},
+		{"trial success closes", []breakerStep{
+			{"fail", BreakerClosed, 0},
+			{"fail", BreakerOpen, 1},
+			{"cooldown", BreakerHalfOpen, 1},
+			{"ok", BreakerClosed, 3},
+		}},
+		{"trial failure reopens", []breakerStep{
+			{"fail", BreakerClosed, 0},
+			{"fail", BreakerOpen, 1},
+			{"cooldown", BreakerHalfOpen, 1},
+			{"fail", BreakerOpen, 3},
+			{"rejected", BreakerOpen, 3},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes := 0
+			b := &Breaker{Threshold: 2, Cooldown: cooldown, OnStateChange: func(from, to BreakerState) {
+				changes++
+			}}
+			for i, step := range tt.steps {
+				switch step.op {
+				case "cooldown":
+					time.Sleep(cooldown)
+				case "rejected":
+					if err := b.allow(); err != ErrBreakerOpen {
+						t.Fatalf("step %d: allow = %v, want %v", i, err, ErrBreakerOpen)
+					}
+				default:
+					if err := b.allow(); err != nil {
+						t.Fatalf("step %d: allow = %v", i, err)
+					}
+					b.done(step.op == "fail")
+				}
+				if got := b.State(); got != step.want {
+					t.Errorf("step %d (%s): state = %v, want %v", i, step.op, got, step.want)
+				}
+				if changes != step.changes {
+					t.Errorf("step %d (%s): %d state changes, want %d", i, step.op, changes, step.changes)
+				}
+			}
+		})
+	}
+}
+
+func TestBreakerSingleTrial(t *testing.T) {
+	b := &Breaker{Threshold: 1, Cooldown: time.Millisecond}
+	b.allow()
+	b.done(true)
+	time.Sleep(time.Millisecond)
+	if err := b.allow(); err != nil {
+		t.Fatalf("trial allow = %v", err)
+	}
+	if err := b.allow(); err != ErrBreakerOpen {
+		t.Errorf("allow during trial = %v, want %v", err, ErrBreakerOpen)
+	}
+}
+
+func TestCallChecksBreakerBeforeThrottle(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	// Tier1 allows one call a minute, so a second call would block if it
+	// waited for the throttle.
+	c := NewClient("xoxb-test", WithBreaker(&Breaker{Threshold: 1, Cooldown: time.Minute}))
+	c.url = srv.URL + "/"
+	if err := c.Call("apps.connections.open", nil, nil); err == nil {
+		t.Fatal("Call succeeded on a server error")
+	}
+	done := make(chan error, 1)
+	go func() { done <- c.Call("apps.connections.open", nil, nil) }()
+	select {
+	case err := <-done:
+		if err != ErrBreakerOpen {
+			t.Errorf("Call = %v, want %v", err, ErrBreakerOpen)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Call waited for the throttle while the breaker was open")
+	}
+	if calls != 1 {
+		t.Errorf("server called %d times, want 1", calls)
+	}
+}
@@ -0,0 +1,29 @@
+package api
+
+import (
+	"net/url"
+
+	"github.com/gopackage/slack/types"
+)
+
+// PostMessageResponse is received from the chat.postMessage API.
+type PostMessageResponse struct {
+	Response
+	// Channel is the ID of the channel the message was posted to
+	Channel types.ChannelID `json:"channel"`
+	// TS is the timestamp of the posted message
+	TS types.Timestamp `json:"ts"`
+	// Message is the message as posted
+	Message types.Message `json:"message"`
+}
+
+// PostMessage posts a message to a channel as the authenticated user or bot.
+func (c *Client) PostMessage(channel types.ChannelID, text string) (*PostMessageResponse, error) {
+	args := url.Values{"channel": {string(channel)}, "text": {text}}
+	var r PostMessageResponse
+	err := c.Call("chat.postMessage", args, &r)
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
@@ -0,0 +1,178 @@
+package api
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gopackage/slack/types"
+)
+
+// HistoryParams contains the optional arguments for conversations.history.
+type HistoryParams struct {
+	// Oldest only includes messages after this timestamp
+	Oldest types.Timestamp
+	// Latest only includes messages before this timestamp
+	Latest types.Timestamp
+	// Inclusive includes messages with the Oldest or Latest timestamps
+	Inclusive bool
+	// Limit is the maximum number of messages to return per page
+	Limit int
+	// Cursor selects the page of results to return
+	Cursor string
+}
+
+// HistoryResponse is received from the conversations.history API.
+type HistoryResponse struct {
+	Response
+	// Messages in the conversation, newest first
+	Messages []types.Message `json:"messages"`
+	// HasMore is true if there are more messages to page through
+	HasMore bool `json:"has_more"`
+}
+
+// ConversationHistory fetches a page of messages posted to a conversation.
+func (c *Client) ConversationHistory(channel types.ChannelID, params HistoryParams) (*HistoryResponse, error) {
+	args := url.Values{"channel": {string(channel)}}
+	if params.Oldest != "" {
+		args.Set("oldest", params.Oldest.String())
+	}
+	if params.Latest != "" {
+		args.Set("latest", params.Latest.String())
+	}
+	if params.Inclusive {
+		args.Set("inclusive", "true")
+	}
+	if params.Limit > 0 {
+		args.Set("limit", strconv.Itoa(params.Limit))
+	}
+	if params.Cursor != "" {
+		args.Set("cursor", params.Cursor)
+	}
+	var r HistoryResponse
+	err := c.Call("conversations.history", args, &r)
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// OpenResponse is received from the conversations.open API.
+type OpenResponse struct {
+	Response
+	// Channel is the opened IM or multi-person IM. Only the ID is set unless
+	// the conversation was opened with return_im.
+	Channel types.Channel `json:"channel"`
+	// AlreadyOpen is true if the conversation was already open
+	AlreadyOpen bool `json:"already_open,omitempty"`
+}
+
+// OpenConversation opens (or resumes) a direct message with one user or a
+// multi-person direct message with several users.
+func (c *Client) OpenConversation(users ...types.UserID) (*OpenResponse, error) {
+	args := url.Values{"users": {joinUsers(users)}}
+	var r OpenResponse
+	err := c.Call("conversations.open", args, &r)
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// channelResponse is received from conversations methods that return the
+// updated channel.
+type channelResponse struct {
+	Response
+	Channel types.Channel `json:"channel"`
+}
+
+// callChannel calls a conversations method that returns a channel.
+func (c *Client) callChannel(method string, args url.Values) (*types.Channel, error) {
+	var r channelResponse
+	err := c.Call(method, args, &r)
+	if err != nil {
+		return nil, err
+	}
+	return &r.Channel, nil
+}
+
+// CreateConversation creates a public or private channel. Names must be
+// lowercase without spaces or periods and at most 80 characters.
+func (c *Client) CreateConversation(name string, private bool) (*types.Channel, error) {
+	return c.callChannel("conversations.create", url.Values{"name": {name}, "is_private": {strconv.FormatBool(private)}})
+}
+
+// ArchiveConversation archives a channel.
+func (c *Client) ArchiveConversation(channel types.ChannelID) error {
+	return c.Call("conversations.archive", url.Values{"channel": {string(channel)}}, nil)
+}
+
+// UnarchiveConversation reverses archiving a channel.
+func (c *Client) UnarchiveConversation(channel types.ChannelID) error {
+	return c.Call("conversations.unarchive", url.Values{"channel": {string(channel)}}, nil)
+}
+
+// InviteToConversation invites one or more users to a channel.
+func (c *Client) InviteToConversation(channel types.ChannelID, users ...types.UserID) (*types.Channel, error) {
+	return c.callChannel("conversations.invite", url.Values{"channel": {string(channel)}, "users": {joinUsers(users)}})
+}
+
+// KickFromConversation removes a user from a channel.
+func (c *Client) KickFromConversation(channel types.ChannelID, user types.UserID) error {
+	return c.Call("conversations.kick", url.Values{"channel": {string(channel)}, "user": {string(user)}}, nil)
+}
+
+// SetConversationTopic sets the topic of a channel.
+func (c *Client) SetConversationTopic(channel types.ChannelID, topic string) (*types.Channel, error) {
+	return c.callChannel("conversations.setTopic", url.Values{"channel": {string(channel)}, "topic": {topic}})
+}
+
+// SetConversationPurpose sets the purpose of a channel.
+func (c *Client) SetConversationPurpose(channel types.ChannelID, purpose string) (*types.Channel, error) {
+	return c.callChannel("conversations.setPurpose", url.Values{"channel": {string(channel)}, "purpose": {purpose}})
+}
+
+// RenameConversation renames a channel.
+func (c *Client) RenameConversation(channel types.ChannelID, name string) (*types.Channel, error) {
+	return c.callChannel("conversations.rename", url.Values{"channel": {string(channel)}, "name": {name}})
+}
+
+// JoinConversation joins the authenticated user or bot to a public channel.
+func (c *Client) JoinConversation(channel types.ChannelID) (*types.Channel, error) {
+	return c.callChannel("conversations.join", url.Values{"channel": {string(channel)}})
+}
+
+// joinUsers formats user IDs as a comma separated list.
+func joinUsers(users []types.UserID) string {
+	ids := make([]string, len(users))
+	for i, user := range users {
+		ids[i] = string(user)
+	}
+	return strings.Join(ids, ",")
+}
+
+// MembersResponse is received from the conversations.members API.
+type MembersResponse struct {
+	Response
+	// Members are the IDs of the users in the conversation
+	Members []types.UserID `json:"members"`
+}
+
+// ConversationMembers fetches a page of the members of a conversation.
+// An empty cursor fetches the first page and a limit of zero uses Slack's
+// default page size.
+func (c *Client) ConversationMembers(channel types.ChannelID, cursor string, limit int) (*MembersResponse, error) {
+	args := url.Values{"channel": {string(channel)}}
+	if cursor != "" {
+		args.Set("cursor", cursor)
+	}
+	if limit > 0 {
+		args.Set("limit", strconv.Itoa(limit))
+	}
+	var r MembersResponse
+	err := c.Call("conversations.members", args, &r)
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
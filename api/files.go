@@ -0,0 +1,202 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gopackage/slack/types"
+)
+
+// ErrFileTooLarge is returned by DownloadFile when the file is larger than
+// the allowed size.
+var ErrFileTooLarge = errors.New("slack: file exceeds maximum download size")
+
+// ErrNotSlackURL is returned by DownloadFile when the file's URL isn't a
+// Slack HTTPS URL, so the client's token isn't sent anywhere else.
+var ErrNotSlackURL = errors.New("slack: file URL is not on slack.com")
+
+// ContentTypeError is returned by DownloadFile when the file's content type
+// isn't one of the allowed types. Slack serves an HTML sign-in page with
+// status 200 when the token can't access the file, so this is also the
+// usual symptom of a missing files:read scope.
+type ContentTypeError struct {
+	// ContentType is the media type of the response e.g. "text/html"
+	ContentType string
+}
+
+// Error implements the error interface.
+func (e *ContentTypeError) Error() string {
+	return fmt.Sprintf("slack: file has unexpected content type %q", e.ContentType)
+}
+
+// DownloadOptions controls the checks made by DownloadFile.
+type DownloadOptions struct {
+	// MaxSize is the largest number of bytes that will be downloaded. Zero
+	// means no limit.
+	MaxSize int64
+	// ContentTypes lists the allowed media types, e.g. "text/csv" or
+	// "text/*". An empty list allows any type except text/html.
+	ContentTypes []string
+}
+
+// DownloadFile streams the contents of the file to w, authenticating with
+// the client's token. The number of bytes written is returned. If the
+// download exceeds opts.MaxSize ErrFileTooLarge is returned after MaxSize
+// bytes have been written, so callers writing to a file should discard it.
+// Only files served over HTTPS from slack.com or one of its subdomains,
+// such as files.slack.com, are downloaded.
+func (c *Client) DownloadFile(file *types.File, w io.Writer, opts DownloadOptions) (int64, error) {
+	if opts.MaxSize > 0 && file.Size > opts.MaxSize {
+		return 0, ErrFileTooLarge
+	}
+	fileURL := file.URLPrivateDownload
+	if fileURL == "" {
+		fileURL = file.URLPrivate
+	}
+	if fileURL == "" {
+		return 0, fmt.Errorf("slack: file %s has no private URL", file.ID)
+	}
+	if !isSlackURL(fileURL) {
+		return 0, ErrNotSlackURL
+	}
+	req, err := http.NewRequest("GET", fileURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	resp, err := c.do("", req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("slack: downloading file %s returned HTTP status %s", file.ID, resp.Status)
+	}
+	err = checkContentType(resp.Header.Get("Content-Type"), opts.ContentTypes)
+	if err != nil {
+		return 0, err
+	}
+	if opts.MaxSize > 0 && resp.ContentLength > opts.MaxSize {
+		return 0, ErrFileTooLarge
+	}
+
+	if opts.MaxSize <= 0 {
+		return io.Copy(w, resp.Body)
+	}
+	n, err := io.Copy(w, io.LimitReader(resp.Body, opts.MaxSize))
+	if err != nil {
+		return n, err
+	}
+	// Anything left over means the file is bigger than allowed.
+	var extra [1]byte
+	if m, _ := resp.Body.Read(extra[:]); m > 0 {
+		return n, ErrFileTooLarge
+	}
+	return n, nil
+}
+
+// checkContentType returns a *ContentTypeError if the content type isn't
+// allowed.
+func checkContentType(contentType string, allowed []string) error {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.TrimSpace(strings.ToLower(contentType))
+	}
+	if len(allowed) == 0 {
+		if mediaType == "text/html" {
+			return &ContentTypeError{ContentType: mediaType}
+		}
+		return nil
+	}
+	for _, a := range allowed {
+		a = strings.ToLower(a)
+		if a == mediaType {
+			return nil
+		}
+		if strings.HasSuffix(a, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(a, "*")) {
+			return nil
+		}
+	}
+	return &ContentTypeError{ContentType: mediaType}
+}
+
+// uploadURLResponse is received from the files.getUploadURLExternal API.
+type uploadURLResponse struct {
+	Response
+	UploadURL string `json:"upload_url"`
+	FileID    string `json:"file_id"`
+}
+
+// completeUploadResponse is received from the files.completeUploadExternal API.
+type completeUploadResponse struct {
+	Response
+	Files []types.File `json:"files"`
+}
+
+// UploadSnippet uploads content as a text snippet and shares it to the
+// channel. The snippet type is a language hint used for syntax
+// highlighting such as "go", "diff" or "text". The shared file is returned.
+func (c *Client) UploadSnippet(channel types.ChannelID, content, snippetType, title string) (*types.File, error) {
+	filename := "snippet.txt"
+	if snippetType != "" && snippetType != "text" {
+		filename = "snippet." + snippetType
+	}
+	args := url.Values{"filename": {filename}, "length": {strconv.Itoa(len(content))}}
+	if snippetType != "" {
+		args.Set("snippet_type", snippetType)
+	}
+	var u uploadURLResponse
+	err := c.Call("files.getUploadURLExternal", args, &u)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", u.UploadURL, strings.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	resp, err := c.do("", req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("slack: uploading snippet returned HTTP status %s", resp.Status)
+	}
+
+	if title == "" {
+		title = filename
+	}
+	files, err := json.Marshal([]map[string]string{{"id": u.FileID, "title": title}})
+	if err != nil {
+		return nil, err
+	}
+	var r completeUploadResponse
+	err = c.Call("files.completeUploadExternal", url.Values{"files": {string(files)}, "channel_id": {string(channel)}}, &r)
+	if err != nil {
+		return nil, err
+	}
+	if len(r.Files) == 0 {
+		return &types.File{ID: u.FileID, Title: title}, nil
+	}
+	return &r.Files[0], nil
+}
+
+// isSlackURL returns true if rawURL is an HTTPS URL on slack.com or one of
+// its subdomains.
+func isSlackURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "https" {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	return host == "slack.com" || strings.HasSuffix(host, ".slack.com")
+}
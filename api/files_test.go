@@ -0,0 +1,71 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gopackage/slack/types"
+)
+
+func TestIsSlackURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://files.slack.com/files-pri/T1-F1/report.csv", true},
+		{"https://slack.com/files/report.csv", true},
+		{"https://FILES.SLACK.COM/files-pri/T1-F1/report.csv", true},
+		{"http://files.slack.com/files-pri/T1-F1/report.csv", false},
+		{"https://files.slack.com.example.com/report.csv", false},
+		{"https://notslack.com/report.csv", false},
+		{"https://example.com/?files.slack.com", false},
+		{"https://user@example.com:443/.slack.com", false},
+		{"files.slack.com/report.csv", false},
+		{"://bad", false},
+	}
+	for _, tt := range tests {
+		if got := isSlackURL(tt.url); got != tt.want {
+			t.Errorf("isSlackURL(%q) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+}
+
+// toServer sends every request to srv instead of the requested host.
+type toServer struct{ srv *httptest.Server }
+
+func (s toServer) RoundTrip(req *http.Request) (*http.Response, error) {
+	u, _ := url.Parse(s.srv.URL)
+	req = req.Clone(req.Context())
+	req.URL.Scheme = u.Scheme
+	req.URL.Host = u.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestDownloadFile(t *testing.T) {
+	var auth []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = append(auth, r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "text/csv")
+		w.Write([]byte("a,b\n1,2\n"))
+	}))
+	defer srv.Close()
+	c := NewClient("xoxb-test", WithHTTPClient(&http.Client{Transport: toServer{srv}}))
+
+	var buf bytes.Buffer
+	file := &types.File{ID: "F1", URLPrivate: "https://files.slack.com/files-pri/T1-F1/report.csv"}
+	n, err := c.DownloadFile(file, &buf, DownloadOptions{})
+	if err != nil || n != 8 || buf.String() != "a,b\n1,2\n" {
+		t.Errorf("DownloadFile = %d, %v with %q", n, err, buf.String())
+	}
+
+	file = &types.File{ID: "F2", URLPrivate: "https://attacker.example.com/report.csv"}
+	if _, err := c.DownloadFile(file, &buf, DownloadOptions{}); err != ErrNotSlackURL {
+		t.Errorf("DownloadFile from another host = %v, want %v", err, ErrNotSlackURL)
+	}
+	if len(auth) != 1 || auth[0] != "Bearer xoxb-test" {
+		t.Errorf("server saw Authorization headers %q, want one with the token", auth)
+	}
+}
@@ -0,0 +1,147 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gopackage/slack/types"
+)
+
+// DefaultMembersConcurrency is the number of channels fetched at once by
+// AllConversationMembers when no concurrency is given.
+const DefaultMembersConcurrency = 4
+
+// membersPageSize is the page size requested from conversations.members.
+const membersPageSize = 1000
+
+// MembersError is returned by AllConversationMembers when the members of
+// some channels could not be fetched.
+type MembersError struct {
+	// Errors holds the error for each channel that failed
+	Errors map[types.ChannelID]error
+}
+
+// Error implements the error interface.
+func (e *MembersError) Error() string {
+	return fmt.Sprintf("slack: failed to fetch members of %d channels", len(e.Errors))
+}
+
+// AllConversationMembers fetches the members of every channel, paging
+// through conversations.members with at most concurrency requests in
+// flight. When Slack reports that the rate limit was hit, all requests
+// pause for the time Slack asked for and the request is retried.
+//
+// The members of the channels that could be fetched are always returned.
+// If any channel failed a *MembersError describing the failures is also
+// returned. Cancelling the context stops fetching and returns the context's
+// error.
+func (c *Client) AllConversationMembers(ctx context.Context, channels []types.ChannelID, concurrency int) (map[types.ChannelID][]types.UserID, error) {
+	if concurrency < 1 {
+		concurrency = DefaultMembersConcurrency
+	}
+	f := &membersFetcher{client: c, ctx: ctx}
+	members := make(map[types.ChannelID][]types.UserID, len(channels))
+	failed := make(map[types.ChannelID]error)
+	var mu sync.Mutex
+
+	work := make(chan types.ChannelID)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for channel := range work {
+				users, err := f.members(channel)
+				mu.Lock()
+				if err != nil {
+					failed[channel] = err
+				} else {
+					members[channel] = users
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+feed:
+	for _, channel := range channels {
+		select {
+		case work <- channel:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(work)
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return members, ctx.Err()
+	}
+	if len(failed) > 0 {
+		return members, &MembersError{Errors: failed}
+	}
+	return members, nil
+}
+
+// membersFetcher pages through channel members, sharing rate limit
+// back-off between all of the goroutines using it.
+type membersFetcher struct {
+	client *Client
+	ctx    context.Context
+
+	mu      sync.Mutex
+	resumed time.Time // no requests are made before this time
+}
+
+// members fetches every page of members of the channel.
+func (f *membersFetcher) members(channel types.ChannelID) ([]types.UserID, error) {
+	var users []types.UserID
+	cursor := ""
+	for {
+		err := f.wait()
+		if err != nil {
+			return nil, err
+		}
+		r, err := f.client.ConversationMembers(channel, cursor, membersPageSize)
+		if limited, ok := err.(*RateLimitedError); ok {
+			f.backOff(limited.RetryAfter)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, r.Members...)
+		cursor = r.Metadata.NextCursor
+		if cursor == "" {
+			return users, nil
+		}
+	}
+}
+
+// wait blocks until requests may be made again or the context is done.
+func (f *membersFetcher) wait() error {
+	f.mu.Lock()
+	delay := time.Until(f.resumed)
+	f.mu.Unlock()
+	if delay <= 0 {
+		return f.ctx.Err()
+	}
+	t := time.NewTimer(delay)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-f.ctx.Done():
+		return f.ctx.Err()
+	}
+}
+
+// backOff pauses all requests for d.
+func (f *membersFetcher) backOff(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if resume := time.Now().Add(d); resume.After(f.resumed) {
+		f.resumed = resume
+	}
+}
@@ -0,0 +1,36 @@
+package api
+
+import (
+	"strings"
+
+	"github.com/gopackage/slack/types"
+)
+
+const (
+	// MaxInlineSnippetLines is the most lines PostSnippet posts inline as a
+	// code block. Longer text is uploaded as a snippet file.
+	MaxInlineSnippetLines = 15
+	// MaxInlineSnippetLength is the most bytes PostSnippet posts inline as a
+	// code block. Longer text is uploaded as a snippet file.
+	MaxInlineSnippetLength = 2000
+)
+
+// PostSnippet posts command output such as logs or diffs to the channel
+// in a readable form. Short text is posted as a fenced code block and
+// longer text is uploaded as a snippet with lang as the syntax hint (e.g.
+// "diff", "go" or "text"). The returned file is nil when the text was
+// posted inline.
+func (c *Client) PostSnippet(channel types.ChannelID, text, lang string) (*types.File, error) {
+	text = strings.TrimRight(text, "\n")
+	if len(text) <= MaxInlineSnippetLength && strings.Count(text, "\n") < MaxInlineSnippetLines {
+		_, err := c.PostMessage(channel, codeBlock(text))
+		return nil, err
+	}
+	return c.UploadSnippet(channel, text, lang, "")
+}
+
+// codeBlock fences text as a Slack code block. Backtick fences inside the
+// text are broken up with a zero width space so they can't end the block.
+func codeBlock(text string) string {
+	return "```\n" + strings.Replace(text, "```", "`\u200b``", -1) + "\n```"
+}
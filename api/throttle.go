@@ -0,0 +1,144 @@
+package api
+
+import (
+	"context"
+	"sync"
+
+	"github.com/gopackage/slack/internal/ratelimit"
+)
+
+// Tier is one of the rate limit tiers Slack assigns to Web API methods.
+type Tier int
+
+// Rate limit tiers. See https://api.slack.com/docs/rate-limits.
+const (
+	// TierSpecial methods have their own limits, such as chat.postMessage
+	// which allows about one message per second per channel. They are
+	// not throttled unless a limit is configured for them, but are still
+	// paused when Slack rejects a call with a Retry-After.
+	TierSpecial Tier = iota
+	// Tier1 methods allow at least 1 call per minute.
+	Tier1
+	// Tier2 methods allow at least 20 calls per minute.
+	Tier2
+	// Tier3 methods allow at least 50 calls per minute.
+	Tier3
+	// Tier4 methods allow at least 100 calls per minute.
+	Tier4
+)
+
+// DefaultTier is the tier assumed for methods missing from MethodTiers.
+const DefaultTier = Tier3
+
+// TierLimit is the rate a Throttle allows calls to each method of a tier.
+type TierLimit struct {
+	// PerMinute is the average number of calls allowed per minute. Zero
+	// means no limit.
+	PerMinute float64
+	// Burst is the number of calls that may be made at once.
+	Burst int
+}
+
+// DefaultTierLimits are the limits used by a Throttle for tiers missing
+// from its Limits. They are Slack's documented rates with small bursts.
+var DefaultTierLimits = map[Tier]TierLimit{
+	Tier1: {PerMinute: 1, Burst: 1},
+	Tier2: {PerMinute: 20, Burst: 3},
+	Tier3: {PerMinute: 50, Burst: 5},
+	Tier4: {PerMinute: 100, Burst: 10},
+}
+
+// MethodTiers are the tiers of the Web API methods used by this package
+// and other common methods.
+var MethodTiers = map[string]Tier{
+	"apps.connections.open":        Tier1,
+	"auth.test":                    TierSpecial,
+	"chat.postMessage":             TierSpecial,
+	"chat.update":                  Tier3,
+	"chat.delete":                  Tier3,
+	"conversations.archive":        Tier2,
+	"conversations.create":         Tier2,
+	"conversations.history":        Tier3,
+	"conversations.info":           Tier3,
+	"conversations.invite":         Tier3,
+	"conversations.join":           Tier3,
+	"conversations.kick":           Tier3,
+	"conversations.list":           Tier2,
+	"conversations.members":        Tier4,
+	"conversations.open":           Tier3,
+	"conversations.rename":         Tier2,
+	"conversations.replies":        Tier3,
+	"conversations.setPurpose":     Tier2,
+	"conversations.setTopic":       Tier2,
+	"conversations.unarchive":      Tier2,
+	"files.completeUploadExternal": Tier4,
+	"files.getUploadURLExternal":   Tier4,
+	"reactions.add":                Tier3,
+	"users.info":                   Tier4,
+	"users.list":                   Tier2,
+}
+
+// Throttle paces Web API calls so each method stays under the limit of
+// its rate limit tier, making callers wait instead of being rejected with
+// HTTP 429. Slack counts calls per method, so each method has its own
+// token bucket. When Slack rejects a call anyway the method is paused for
+// the Retry-After period it asked for.
+//
+// The zero value uses MethodTiers and DefaultTierLimits. A Throttle may be
+// shared by several clients using the same token and is safe for
+// concurrent use, but its fields must not be changed once it is in use.
+type Throttle struct {
+	// Tiers overrides the tier of methods, keyed by method name.
+	Tiers map[string]Tier
+	// Limits overrides the limits of tiers.
+	Limits map[Tier]TierLimit
+
+	mu       sync.Mutex
+	limiters map[string]*ratelimit.Limiter
+}
+
+// Tier returns the tier of the method.
+func (t *Throttle) Tier(method string) Tier {
+	if tier, ok := t.Tiers[method]; ok {
+		return tier
+	}
+	if tier, ok := MethodTiers[method]; ok {
+		return tier
+	}
+	return DefaultTier
+}
+
+// limit returns the limit of the tier.
+func (t *Throttle) limit(tier Tier) TierLimit {
+	if limit, ok := t.Limits[tier]; ok {
+		return limit
+	}
+	return DefaultTierLimits[tier]
+}
+
+// limiter returns the method's token bucket. Methods without a limit get
+// a bucket that never runs out so they can still be paused.
+func (t *Throttle) limiter(method string) *ratelimit.Limiter {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if l, ok := t.limiters[method]; ok {
+		return l
+	}
+	limit := t.limit(t.Tier(method))
+	l := ratelimit.New(limit.PerMinute/60, limit.Burst)
+	if t.limiters == nil {
+		t.limiters = make(map[string]*ratelimit.Limiter)
+	}
+	t.limiters[method] = l
+	return l
+}
+
+// wait blocks until the method may be called.
+func (t *Throttle) wait(method string) {
+	t.limiter(method).Wait(context.Background())
+}
+
+// limited pauses the method after Slack rejected a call to it.
+func (t *Throttle) limited(err *RateLimitedError) {
+	t.limiter(err.Method).Pause(err.RetryAfter)
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"testing"
+	"time"
+)
+
+func TestThrottleTier(t *testing.T) {
+	th := &Throttle{Tiers: map[string]Tier{"users.list": Tier4, "custom.method": Tier1}}
+	tests := []struct {
+		method string
+		want   Tier
+	}{
+		{"users.list", Tier4},
+		{"custom.method", Tier1},
+		{"chat.postMessage", TierSpecial},
+		{"conversations.list", Tier2},
+		{"unknown.method", DefaultTier},
+	}
+	for _, tt := range tests {
+		if got := th.Tier(tt.method); got != tt.want {
+			t.Errorf("Tier(%q) = %v, want %v", tt.method, got, tt.want)
+		}
+	}
+}
+
+func TestThrottleLimit(t *testing.T) {
+	th := &Throttle{Limits: map[Tier]TierLimit{Tier2: {PerMinute: 10, Burst: 2}, TierSpecial: {PerMinute: 60, Burst: 1}}}
+	tests := []struct {
+		tier Tier
+		want TierLimit
+	}{
+		{Tier1, DefaultTierLimits[Tier1]},
+		{Tier2, TierLimit{PerMinute: 10, Burst: 2}},
+		{Tier4, DefaultTierLimits[Tier4]},
+		{TierSpecial, TierLimit{PerMinute: 60, Burst: 1}},
+	}
+	for _, tt := range tests {
+		if got := th.limit(tt.tier); got != tt.want {
+			t.Errorf("limit(%v) = %+v, want %+v", tt.tier, got, tt.want)
+		}
+	}
+	if got := (&Throttle{}).limit(TierSpecial); got.PerMinute != 0 {
+		t.Errorf("default special limit = %+v, want no limit", got)
+	}
+}
+
+func TestThrottleLimited(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+	}{
+		{"tiered", "conversations.history"},
+		{"special", "chat.postMessage"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			th := &Throttle{}
+			th.limited(&RateLimitedError{Method: tt.method, RetryAfter: 100 * time.Millisecond})
+			start := time.Now()
+			th.wait(tt.method)
+			if got := time.Since(start); got < 90*time.Millisecond {
+				t.Errorf("wait returned after %v, before Retry-After", got)
+			}
+			// Other methods are limited separately.
+			start = time.Now()
+			th.wait("users.info")
+			if got := time.Since(start); got > 50*time.Millisecond {
+				t.Errorf("wait for another method took %v", got)
+			}
+		})
+	}
+}
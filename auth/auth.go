@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
+
+	"github.com/gopackage/slack/types"
 )
 
 // VerifyToken determines of the provided token is valid
@@ -24,19 +26,20 @@ func VerifyToken(token string) (bool, error) {
 }
 
 // Response encapsulates the `auth.test` Slack web API response.
-// {
-//   "ok":true,
-//   "url":"https:\/\/intellimatics.slack.com\/",
-//   "team":"Intellimatics",
-//   "user":"bitbot",
-//   "team_id":"T024FL887",
-//   "user_id":"U03AHNBPC"
-// }
+//
+//	{
+//	  "ok":true,
+//	  "url":"https:\/\/intellimatics.slack.com\/",
+//	  "team":"Intellimatics",
+//	  "user":"bitbot",
+//	  "team_id":"T024FL887",
+//	  "user_id":"U03AHNBPC"
+//	}
 type Response struct {
-	Ok     bool   `json:"ok"`
-	URL    string `json:"url"`
-	Team   string `json:"team"`
-	User   string `json:"user"`
-	TeamID string `json:"team_id"`
-	UserID string `json:"user_id"`
+	Ok     bool         `json:"ok"`
+	URL    string       `json:"url"`
+	Team   string       `json:"team"`
+	User   string       `json:"user"`
+	TeamID types.TeamID `json:"team_id"`
+	UserID types.UserID `json:"user_id"`
 }
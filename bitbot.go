@@ -1,10 +1,20 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"io"
+	"io/ioutil"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 
+	"github.com/gopackage/slack/api"
 	"github.com/gopackage/slack/auth"
+	"github.com/gopackage/slack/bot"
+	_ "github.com/gopackage/slack/plugins/ping"
 	"github.com/gopackage/slack/rtm"
 )
 
@@ -13,17 +23,16 @@ const (
 	SlackAPIVersion = "1"
 	// BitbotVersion is the version of the bitbot library
 	BitbotVersion = "0.0.1"
-	// TokenKey is the name of the token environmental variable
+	// TokenKey is the name of the default token environmental variable
 	TokenKey = "BITBOT_TOKEN"
 )
 
 // Slack does stuff - nice huh?
-func Slack() {
-	// Pull in the auth token from the environment
-	token := os.Getenv(TokenKey)
-	if len(token) == 0 {
+func Slack(config *Config) {
+	token, err := config.Token()
+	if err != nil {
 		// Bail
-		log.Fatalln("Failed to read env variable", TokenKey)
+		log.Fatalln(err)
 	}
 	verified, err := auth.VerifyToken(token)
 	if err != nil {
@@ -32,11 +41,99 @@ func Slack() {
 	if !verified {
 		log.Fatalln("API token did not verify")
 	}
-	log.Println("token verified")
-	log.Fatalln(rtm.DialAndListen(token))
+	config.info("token verified")
+
+	b := newBot(config, token)
+	for _, channel := range config.JoinChannels {
+		_, err = b.API().JoinConversation(channel)
+		if err != nil {
+			log.Fatalln("Failed to join channel", channel, err)
+		}
+		config.info("joined", channel)
+	}
+
+	if config.HealthAddr != "" {
+		go serveHealth(config.HealthAddr, b.Client())
+		config.info("serving health checks on", config.HealthAddr)
+	}
+
+	// Shut down gracefully when the process is asked to stop so replies
+	// that are being sent aren't lost.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	err = b.Run(ctx)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	config.info("shut down")
+}
+
+// Simulate runs the bot on simulated messages from standard input or a
+// script so plugins can be tried out without a Slack workspace or token.
+func Simulate(config *Config) {
+	var in io.Reader = os.Stdin
+	if config.Script != "" {
+		f, err := os.Open(config.Script)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		defer f.Close()
+		in = f
+	}
+	b := newBot(config, "")
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	err := b.Simulate(ctx, in, os.Stdout, config.Script != "")
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+// serveHealth serves the client's liveness and readiness endpoints on addr.
+func serveHealth(addr string, client *rtm.Client) {
+	mux := http.NewServeMux()
+	mux.Handle("/healthz", rtm.LivenessHandler(client))
+	mux.Handle("/readyz", rtm.ReadinessHandler(client))
+	log.Fatalln(http.ListenAndServe(addr, mux))
+}
+
+// newBot creates a bot with the configured plugins enabled.
+func newBot(config *Config, token string) *bot.Bot {
+	var options []rtm.Option
+	if config.LogLevel != LogDebug {
+		// The RTM client logs every frame which is only useful for
+		// debugging. Its errors still go to standard error.
+		options = append(options, rtm.WithDebugLogger(log.New(ioutil.Discard, "", 0)))
+	}
+	if config.RateLimit.MessagesPerSecond > 0 {
+		options = append(options, rtm.WithSendRate(config.RateLimit.MessagesPerSecond, config.RateLimit.Burst))
+	}
+	options = append(options, rtm.WithAPIClient(api.NewClient(token, api.WithThrottle(config.Throttle()))))
+	b := bot.New(token, options...)
+	plugins := config.EnabledPlugins()
+	for _, name := range plugins {
+		b.SetConfig(name, config.Plugins[name].Settings)
+	}
+	err := b.Enable(plugins...)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	config.info("plugins enabled", plugins)
+	return b
 }
 
 func main() {
-	log.Println("Bitbot", BitbotVersion)
-	Slack()
+	config, err := loadConfig(os.Args[1:], os.Stderr)
+	if err == flag.ErrHelp {
+		os.Exit(0)
+	}
+	if err != nil {
+		log.Fatalln(err)
+	}
+	config.info("Bitbot", BitbotVersion)
+	if config.REPL || config.Script != "" {
+		Simulate(config)
+		return
+	}
+	Slack(config)
 }
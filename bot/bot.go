@@ -0,0 +1,214 @@
+// Package bot builds Slack bots out of plugins running on an RTM client.
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/gopackage/slack/api"
+	"github.com/gopackage/slack/rtm"
+)
+
+const (
+	// DefaultShutdownTimeout is how long Run waits for in-flight work and
+	// plugins to stop once its context is cancelled.
+	DefaultShutdownTimeout = 10 * time.Second
+	// minReconnectDelay and maxReconnectDelay bound the back-off between
+	// reconnection attempts.
+	minReconnectDelay = time.Second
+	maxReconnectDelay = time.Minute
+)
+
+// Config holds the settings for a single plugin.
+type Config map[string]string
+
+// Get returns the value of the setting or def if it isn't set.
+func (c Config) Get(key, def string) string {
+	if v, ok := c[key]; ok {
+		return v
+	}
+	return def
+}
+
+// Bot runs a set of plugins on an RTM connection. Plugins register their
+// handlers on the bot's ServeMux and use its clients to call Slack.
+type Bot struct {
+	// ShutdownTimeout bounds how long Run waits for in-flight work and
+	// plugins to stop. Zero uses DefaultShutdownTimeout.
+	ShutdownTimeout time.Duration
+
+	api     *api.Client
+	client  *rtm.Client
+	mux     *rtm.ServeMux
+	logger  *log.Logger
+	plugins []Plugin
+	configs map[string]Config
+}
+
+// New creates a bot that connects with the token. The RTM client is
+// configured with the provided options and the bot shares its Web API
+// client, which may be set with rtm.WithAPIClient.
+func New(token string, options ...rtm.Option) *Bot {
+	b := &Bot{
+		mux:     rtm.NewServeMux(),
+		logger:  log.New(os.Stderr, "", log.LstdFlags),
+		configs: make(map[string]Config),
+	}
+	options = append([]rtm.Option{rtm.WithLogger(b.logger)}, options...)
+	b.client = rtm.NewClient(token, options...)
+	b.api = b.client.API()
+	return b
+}
+
+// API returns the Web API client used by the bot.
+func (b *Bot) API() *api.Client {
+	return b.api
+}
+
+// Client returns the RTM client used by the bot.
+func (b *Bot) Client() *rtm.Client {
+	return b.client
+}
+
+// Mux returns the ServeMux plugins register their event handlers on.
+func (b *Bot) Mux() *rtm.ServeMux {
+	return b.mux
+}
+
+// Logger returns the logger plugins should report to.
+func (b *Bot) Logger() *log.Logger {
+	return b.logger
+}
+
+// Config returns the settings for the named plugin. The result is never
+// nil.
+func (b *Bot) Config(name string) Config {
+	if c, ok := b.configs[name]; ok {
+		return c
+	}
+	return Config{}
+}
+
+// SetConfig sets the settings for the named plugin. Settings must be set
+// before Run so they are available when the plugin is initialized.
+func (b *Bot) SetConfig(name string, c Config) {
+	b.configs[name] = c
+}
+
+// Use adds the plugin to the bot whether or not it is registered.
+func (b *Bot) Use(p Plugin) {
+	b.plugins = append(b.plugins, p)
+}
+
+// Enable adds the registered plugins with the names to the bot.
+func (b *Bot) Enable(names ...string) error {
+	for _, name := range names {
+		p := Lookup(name)
+		if p == nil {
+			return fmt.Errorf("bot: unknown plugin %q (registered: %v)", name, Plugins())
+		}
+		b.Use(p)
+	}
+	return nil
+}
+
+// Run initializes and starts the plugins and handles events until the
+// context is cancelled, reconnecting when the connection is lost. When the
+// context is cancelled the RTM client is shut down gracefully and the
+// plugins are stopped in reverse order. Run returns nil after a clean
+// shutdown.
+func (b *Bot) Run(ctx context.Context) error {
+	return b.run(ctx, b.listen)
+}
+
+// run initializes and starts the plugins, calls serve and then stops the
+// plugins that were started in reverse order.
+func (b *Bot) run(ctx context.Context, serve func(ctx context.Context) error) error {
+	for _, p := range b.plugins {
+		err := p.Init(b)
+		if err != nil {
+			return fmt.Errorf("bot: plugin %s failed to initialize: %v", p.Name(), err)
+		}
+	}
+	started := 0
+	var err error
+	for _, p := range b.plugins {
+		err = p.Start(ctx)
+		if err != nil {
+			err = fmt.Errorf("bot: plugin %s failed to start: %v", p.Name(), err)
+			break
+		}
+		started++
+	}
+	if err == nil {
+		err = serve(ctx)
+	}
+
+	stopCtx, cancel := context.WithTimeout(context.Background(), b.shutdownTimeout())
+	defer cancel()
+	for i := started - 1; i >= 0; i-- {
+		p := b.plugins[i]
+		if stopErr := p.Stop(stopCtx); stopErr != nil {
+			b.logger.Println("bot: plugin", p.Name(), "failed to stop:", stopErr)
+		}
+	}
+	return err
+}
+
+// listen handles events until the context is cancelled, reconnecting with
+// exponential back-off when the connection fails.
+func (b *Bot) listen(ctx context.Context) error {
+	// The connection is shut down gracefully when ctx is done, then closed
+	// by cancelling runCtx in case the shutdown couldn't complete.
+	runCtx, cancelRun := context.WithCancel(context.Background())
+	defer cancelRun()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+			return
+		}
+		defer cancelRun()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), b.shutdownTimeout())
+		defer cancel()
+		if err := b.client.Shutdown(shutdownCtx); err != nil {
+			b.logger.Println("bot: shutdown did not complete:", err)
+		}
+	}()
+
+	delay := minReconnectDelay
+	for {
+		connected := time.Now()
+		err := b.client.Run(runCtx, b.mux)
+		if err == rtm.ErrClientClosed || runCtx.Err() != nil {
+			return nil
+		}
+		b.logger.Println("bot: connection lost:", err)
+		if time.Since(connected) > maxReconnectDelay {
+			// The connection was healthy for a while so start over.
+			delay = minReconnectDelay
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+		delay *= 2
+		if delay > maxReconnectDelay {
+			delay = maxReconnectDelay
+		}
+	}
+}
+
+// shutdownTimeout returns the configured or default shutdown timeout.
+func (b *Bot) shutdownTimeout() time.Duration {
+	if b.ShutdownTimeout > 0 {
+		return b.ShutdownTimeout
+	}
+	return DefaultShutdownTimeout
+}
@@ -0,0 +1,47 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/gopackage/slack/rtm"
+)
+
+// replyPlugin answers a command with a reply.
+type replyPlugin struct {
+	name, command, reply string
+}
+
+func (p *replyPlugin) Name() string { return p.name }
+
+func (p *replyPlugin) Init(b *Bot) error {
+	b.Mux().HandleMessageFunc(func(w rtm.ResponseWriter, m *rtm.MessageEvent) {
+		if m.Text == p.command {
+			w.WriteMsg(m.Channel, p.reply)
+		}
+	})
+	return nil
+}
+
+func (p *replyPlugin) Start(ctx context.Context) error { return nil }
+
+func (p *replyPlugin) Stop(ctx context.Context) error { return nil }
+
+func TestPluginsShareMessageEvents(t *testing.T) {
+	b := New("")
+	b.Use(&replyPlugin{name: "first", command: "!one", reply: "one"})
+	b.Use(&replyPlugin{name: "second", command: "!two", reply: "two"})
+
+	var out bytes.Buffer
+	err := b.Simulate(context.Background(), strings.NewReader("!one\n!two\n"), &out, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"< [C0SIMULATE] one", "< [C0SIMULATE] two"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output %q does not contain %q", out.String(), want)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package bot
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// Plugin is a bot feature, such as karma tracking or deploy commands, that
+// can be developed, enabled and configured independently of other features.
+//
+// The bot calls Init once before connecting so the plugin can read its
+// configuration and register handlers. Start is called once the bot is
+// running and Stop when it shuts down; plugins with background work (such
+// as reminders) start and stop it there. Plugins without background work
+// return nil from Start and Stop.
+type Plugin interface {
+	// Name identifies the plugin in the registry and in configuration.
+	Name() string
+	// Init prepares the plugin to run on the bot.
+	Init(b *Bot) error
+	// Start begins any background work. Start must not block.
+	Start(ctx context.Context) error
+	// Stop ends any background work, returning before the context expires.
+	Stop(ctx context.Context) error
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Plugin)
+)
+
+// Register makes a plugin available by name so it can be enabled with
+// Bot.Enable. Plugin packages typically call Register from an init
+// function so importing the package is enough to make the plugin
+// available. Register panics if the plugin is nil or a plugin with the
+// same name is already registered.
+func Register(p Plugin) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if p == nil {
+		panic("bot: Register plugin is nil")
+	}
+	if _, dup := registry[p.Name()]; dup {
+		panic("bot: Register called twice for plugin " + p.Name())
+	}
+	registry[p.Name()] = p
+}
+
+// Lookup returns the registered plugin with the name, or nil.
+func Lookup(name string) Plugin {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return registry[name]
+}
+
+// Plugins returns a sorted list of the names of the registered plugins.
+func Plugins() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
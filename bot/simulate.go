@@ -0,0 +1,182 @@
+package bot
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/gopackage/slack/rtm"
+	"github.com/gopackage/slack/types"
+)
+
+const (
+	// SimulatedChannel is the channel simulated messages are posted to
+	// until changed with the /channel command.
+	SimulatedChannel types.ChannelID = "C0SIMULATE"
+	// SimulatedUser is the author of simulated messages until changed with
+	// the /user command.
+	SimulatedUser types.UserID = "U0SIMULATE"
+)
+
+// simulateHelp describes the commands understood by Simulate.
+const simulateHelp = `Type a message to send it to the bot. Commands:
+  /channel ID   post following messages to the channel
+  /user ID      post following messages as the user
+  /event JSON   deliver a raw RTM event
+  /help         show this help
+  /quit         stop the simulation`
+
+// Simulate runs the bot without connecting to Slack, for developing and
+// demoing plugins. Each line read from in is delivered to the plugins as a
+// message event, and everything the plugins send is printed to out
+// instead. Lines starting with a slash are commands, see /help. If echo is
+// true the input lines are printed too, which makes a readable transcript
+// when in is a script.
+//
+// Only what plugins send through the ResponseWriter is simulated; plugins
+// that call the Web API or the RTM client directly still reach Slack.
+// Simulate returns when in is exhausted, on /quit or when the context is
+// cancelled.
+func (b *Bot) Simulate(ctx context.Context, in io.Reader, out io.Writer, echo bool) error {
+	return b.run(ctx, func(ctx context.Context) error {
+		s := &simulation{mux: b.mux, w: &printWriter{out: out}, channel: SimulatedChannel, user: SimulatedUser}
+		s.deliver(map[string]interface{}{"type": rtm.EventHello})
+
+		lines := make(chan string)
+		errs := make(chan error, 1)
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			scanner := bufio.NewScanner(in)
+			for scanner.Scan() {
+				select {
+				case lines <- scanner.Text():
+				case <-done:
+					return
+				}
+			}
+			errs <- scanner.Err()
+		}()
+		for {
+			s.w.printf("> ")
+			select {
+			case <-ctx.Done():
+				s.w.printf("\n")
+				return nil
+			case err := <-errs:
+				s.w.printf("\n")
+				return err
+			case line := <-lines:
+				if echo {
+					s.w.printf("%s\n", line)
+				}
+				if !s.handle(strings.TrimSpace(line)) {
+					return nil
+				}
+			}
+		}
+	})
+}
+
+// simulation tracks the state of a running Simulate call.
+type simulation struct {
+	mux     *rtm.ServeMux
+	w       *printWriter
+	channel types.ChannelID
+	user    types.UserID
+	ts      int64
+}
+
+// handle processes a line of input, returning false to stop.
+func (s *simulation) handle(line string) bool {
+	if line == "" {
+		return true
+	}
+	if !strings.HasPrefix(line, "/") {
+		s.ts++
+		s.deliver(&rtm.MessageEvent{Message: types.Message{
+			Type:    rtm.EventMessage,
+			Channel: s.channel,
+			User:    s.user,
+			Text:    line,
+			TS:      types.Timestamp(fmt.Sprintf("%d.000000", s.ts)),
+		}})
+		return true
+	}
+	command, arg := line, ""
+	if i := strings.IndexByte(line, ' '); i > 0 {
+		command, arg = line[:i], strings.TrimSpace(line[i+1:])
+	}
+	switch command {
+	case "/quit":
+		return false
+	case "/channel":
+		s.channel = types.ChannelID(arg)
+	case "/user":
+		s.user = types.UserID(arg)
+	case "/event":
+		event, err := rtm.NewEnvelope([]byte(arg))
+		if err != nil {
+			s.w.printf("invalid event: %v\n", err)
+			return true
+		}
+		s.mux.HandleEvent(s.w, event)
+	default:
+		s.w.printf("%s\n", simulateHelp)
+	}
+	return true
+}
+
+// deliver encodes the event and passes it to the mux.
+func (s *simulation) deliver(v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		s.w.printf("invalid event: %v\n", err)
+		return
+	}
+	event, err := rtm.NewEnvelope(data)
+	if err != nil {
+		s.w.printf("invalid event: %v\n", err)
+		return
+	}
+	s.mux.HandleEvent(s.w, event)
+}
+
+// printWriter is an rtm.ResponseWriter that prints what would be sent.
+type printWriter struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+// printf writes formatted output.
+func (w *printWriter) printf(format string, a ...interface{}) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	fmt.Fprintf(w.out, format, a...)
+}
+
+// Write prints the event as JSON.
+func (w *printWriter) Write(event map[string]interface{}) (int, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return -1, err
+	}
+	w.printf("< %s\n", data)
+	return len(data), nil
+}
+
+// WriteMsg prints the message.
+func (w *printWriter) WriteMsg(channel types.ChannelID, text string) (int, error) {
+	w.printf("< [%s] %s\n", channel, text)
+	return len(text), nil
+}
+
+// WriteTyping prints the typing indicator.
+func (w *printWriter) WriteTyping(channel types.ChannelID) (int, error) {
+	w.printf("< [%s] (typing)\n", channel)
+	return 0, nil
+}
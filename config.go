@@ -0,0 +1,330 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/gopackage/slack/api"
+	"github.com/gopackage/slack/bot"
+	"github.com/gopackage/slack/types"
+	"gopkg.in/yaml.v3"
+)
+
+// Log levels accepted by the log_level setting.
+const (
+	LogDebug = "debug"
+	LogInfo  = "info"
+	LogError = "error"
+)
+
+// Config controls the bitbot binary. It is read from a YAML file and may be
+// overridden by command line flags:
+//
+//	token_file: /run/secrets/slack-token
+//	log_level: info
+//	plugins:
+//	  ping:
+//	    enabled: true
+//	    settings:
+//	      command: "!ping"
+//	join_channels: [C024BE91L]
+//	rate_limit:
+//	  messages_per_second: 1
+//	  burst: 3
+//	  web_api:
+//	    tiers:
+//	      2: {per_minute: 10, burst: 2}
+//	    methods:
+//	      users.list: 1
+//	health_addr: ":8080"
+type Config struct {
+	// TokenEnv names the environment variable holding the API token.
+	// Defaults to BITBOT_TOKEN.
+	TokenEnv string `yaml:"token_env"`
+	// TokenFile is a file holding the API token. It takes precedence over
+	// TokenEnv.
+	TokenFile string `yaml:"token_file"`
+	// LogLevel is one of debug, info or error. Debug includes the RTM
+	// client's logging of every frame.
+	LogLevel string `yaml:"log_level"`
+	// Plugins configures plugins by name. If no plugins are configured
+	// every registered plugin is enabled.
+	Plugins map[string]PluginConfig `yaml:"plugins"`
+	// JoinChannels lists public channel IDs the bot joins at startup.
+	JoinChannels []types.ChannelID `yaml:"join_channels"`
+	// RateLimit limits how fast the bot sends messages and calls the Web
+	// API.
+	RateLimit RateLimitConfig `yaml:"rate_limit"`
+	// HealthAddr is the address to serve the /healthz (liveness) and
+	// /readyz (readiness) endpoints on. Empty disables them.
+	HealthAddr string `yaml:"health_addr"`
+
+	// REPL simulates the bot reading messages from standard input instead
+	// of connecting to Slack. It is only set by the -repl flag.
+	REPL bool `yaml:"-"`
+	// Script simulates the bot reading messages from the file instead of
+	// connecting to Slack. It is only set by the -script flag.
+	Script string `yaml:"-"`
+}
+
+// PluginConfig enables and configures a single plugin.
+type PluginConfig struct {
+	// Enabled adds the plugin to the bot.
+	Enabled bool `yaml:"enabled"`
+	// Settings are passed to the plugin as its bot.Config.
+	Settings bot.Config `yaml:"settings"`
+}
+
+// RateLimitConfig limits how fast the bot sends messages and calls the Web
+// API.
+type RateLimitConfig struct {
+	// MessagesPerSecond is the average number of messages that may be sent
+	// per second. Zero means no limit.
+	MessagesPerSecond float64 `yaml:"messages_per_second"`
+	// Burst is the number of messages that may be sent at once. Defaults
+	// to 1.
+	Burst int `yaml:"burst"`
+	// WebAPI paces Web API calls by the rate limit tier of their method.
+	WebAPI WebAPIRateLimitConfig `yaml:"web_api"`
+}
+
+// WebAPIRateLimitConfig overrides how Web API calls are paced. Calls are
+// paced by api.DefaultTierLimits and api.MethodTiers unless disabled.
+type WebAPIRateLimitConfig struct {
+	// Disabled turns off pacing of Web API calls.
+	Disabled bool `yaml:"disabled"`
+	// Tiers overrides the limits of tiers, keyed by tier: 1 to 4, or 0
+	// for special methods such as chat.postMessage.
+	Tiers map[api.Tier]TierLimitConfig `yaml:"tiers"`
+	// Methods overrides the tier of methods, keyed by method name.
+	Methods map[string]api.Tier `yaml:"methods"`
+}
+
+// TierLimitConfig limits calls to each method of a rate limit tier.
+type TierLimitConfig struct {
+	// PerMinute is the average number of calls allowed per minute. Zero
+	// means no limit.
+	PerMinute float64 `yaml:"per_minute"`
+	// Burst is the number of calls that may be made at once. Defaults
+	// to 1.
+	Burst int `yaml:"burst"`
+}
+
+// loadConfig builds the configuration from the command line arguments and
+// the configuration file they name, if any. Flags override the file.
+func loadConfig(args []string, output io.Writer) (*Config, error) {
+	c := &Config{}
+	flags := flag.NewFlagSet("bitbot", flag.ContinueOnError)
+	flags.SetOutput(output)
+	path := flags.String("config", "", "path to the YAML configuration `file`")
+	tokenEnv := flags.String("token-env", "", "environment `variable` holding the API token (default "+TokenKey+")")
+	tokenFile := flags.String("token-file", "", "`file` holding the API token")
+	logLevel := flags.String("log-level", "", "log `level`: debug, info or error (default info)")
+	plugins := flags.String("plugins", "", "comma separated `list` of plugins to enable, replacing the configured list")
+	join := flags.String("join", "", "comma separated `list` of channel IDs to join, replacing the configured list")
+	healthAddr := flags.String("health-addr", "", "`address` to serve the /healthz and /readyz endpoints on")
+	rate := flags.Float64("rate", -1, "maximum messages sent per `second` (0 for no limit)")
+	noThrottle := flags.Bool("no-api-throttle", false, "don't pace Web API calls by rate limit tier")
+	flags.BoolVar(&c.REPL, "repl", false, "simulate the bot with messages typed on standard input, without connecting to Slack")
+	flags.StringVar(&c.Script, "script", "", "simulate the bot with messages read from `file`, without connecting to Slack")
+	err := flags.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+	if flags.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments: %s", strings.Join(flags.Args(), " "))
+	}
+
+	if *path != "" {
+		data, err := ioutil.ReadFile(*path)
+		if err != nil {
+			return nil, fmt.Errorf("reading config: %v", err)
+		}
+		// Unknown keys are rejected so typos don't go unnoticed.
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		err = dec.Decode(c)
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("parsing config %s: %v", *path, err)
+		}
+	}
+
+	if *tokenEnv != "" {
+		c.TokenEnv = *tokenEnv
+	}
+	if *tokenFile != "" {
+		c.TokenFile = *tokenFile
+	}
+	if *logLevel != "" {
+		c.LogLevel = *logLevel
+	}
+	if *plugins != "" {
+		enabled := make(map[string]PluginConfig)
+		for _, name := range splitList(*plugins) {
+			p := c.Plugins[name]
+			p.Enabled = true
+			enabled[name] = p
+		}
+		c.Plugins = enabled
+	}
+	if *join != "" {
+		c.JoinChannels = nil
+		for _, id := range splitList(*join) {
+			c.JoinChannels = append(c.JoinChannels, types.ChannelID(id))
+		}
+	}
+	if *healthAddr != "" {
+		c.HealthAddr = *healthAddr
+	}
+	if *rate >= 0 {
+		c.RateLimit.MessagesPerSecond = *rate
+	}
+	if *noThrottle {
+		c.RateLimit.WebAPI.Disabled = true
+	}
+
+	if c.TokenEnv == "" {
+		c.TokenEnv = TokenKey
+	}
+	if c.LogLevel == "" {
+		c.LogLevel = LogInfo
+	}
+	if c.RateLimit.Burst == 0 {
+		c.RateLimit.Burst = 1
+	}
+	return c, c.validate()
+}
+
+// validate checks the configuration, reporting every problem found.
+func (c *Config) validate() error {
+	var problems []string
+	switch c.LogLevel {
+	case LogDebug, LogInfo, LogError:
+	default:
+		problems = append(problems, fmt.Sprintf("log_level %q must be one of debug, info or error", c.LogLevel))
+	}
+	names := make([]string, 0, len(c.Plugins))
+	for name := range c.Plugins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if bot.Lookup(name) == nil {
+			problems = append(problems, fmt.Sprintf("unknown plugin %q (available: %s)", name, strings.Join(bot.Plugins(), ", ")))
+		}
+	}
+	for _, id := range c.JoinChannels {
+		// Private channels can have C IDs too, so only the shape of the ID
+		// is checked here; conversations.join fails for private channels.
+		if !id.IsChannel() || id.IsIM() {
+			problems = append(problems, fmt.Sprintf("join_channels entry %q is not a channel ID (like C024BE91L)", id))
+		}
+	}
+	if c.RateLimit.MessagesPerSecond < 0 {
+		problems = append(problems, "rate_limit.messages_per_second must not be negative")
+	}
+	if c.RateLimit.Burst < 1 {
+		problems = append(problems, "rate_limit.burst must be at least 1")
+	}
+	for tier, limit := range c.RateLimit.WebAPI.Tiers {
+		if !validTier(tier) {
+			problems = append(problems, fmt.Sprintf("rate_limit.web_api.tiers has unknown tier %d (must be 0 to 4)", tier))
+		}
+		if limit.PerMinute < 0 || limit.Burst < 0 {
+			problems = append(problems, fmt.Sprintf("rate_limit.web_api.tiers.%d must not be negative", tier))
+		}
+	}
+	for method, tier := range c.RateLimit.WebAPI.Methods {
+		if !validTier(tier) {
+			problems = append(problems, fmt.Sprintf("rate_limit.web_api.methods.%s has unknown tier %d (must be 0 to 4)", method, tier))
+		}
+	}
+	if len(problems) > 0 {
+		return errors.New("invalid configuration:\n  " + strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// validTier reports whether tier is one of Slack's rate limit tiers.
+func validTier(tier api.Tier) bool {
+	return tier >= api.TierSpecial && tier <= api.Tier4
+}
+
+// Throttle returns the throttle pacing Web API calls, or nil if pacing is
+// disabled.
+func (c *Config) Throttle() *api.Throttle {
+	if c.RateLimit.WebAPI.Disabled {
+		return nil
+	}
+	t := &api.Throttle{Tiers: c.RateLimit.WebAPI.Methods}
+	if len(c.RateLimit.WebAPI.Tiers) > 0 {
+		t.Limits = make(map[api.Tier]api.TierLimit)
+		for tier, limit := range c.RateLimit.WebAPI.Tiers {
+			t.Limits[tier] = api.TierLimit{PerMinute: limit.PerMinute, Burst: limit.Burst}
+		}
+	}
+	return t
+}
+
+// EnabledPlugins returns the sorted names of the plugins to enable, so
+// they are started and stopped in the same order every run. Every
+// registered plugin is enabled if no plugins are configured.
+func (c *Config) EnabledPlugins() []string {
+	if len(c.Plugins) == 0 {
+		return bot.Plugins()
+	}
+	var names []string
+	for name, p := range c.Plugins {
+		if p.Enabled {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Token reads the API token from the configured source.
+func (c *Config) Token() (string, error) {
+	if c.TokenFile != "" {
+		data, err := ioutil.ReadFile(c.TokenFile)
+		if err != nil {
+			return "", fmt.Errorf("reading token: %v", err)
+		}
+		token := strings.TrimSpace(string(data))
+		if token == "" {
+			return "", fmt.Errorf("token file %s is empty", c.TokenFile)
+		}
+		return token, nil
+	}
+	token := os.Getenv(c.TokenEnv)
+	if token == "" {
+		return "", fmt.Errorf("no API token: set the %s environment variable or configure token_file", c.TokenEnv)
+	}
+	return token, nil
+}
+
+// info logs informational messages unless only errors are wanted.
+func (c *Config) info(v ...interface{}) {
+	if c.LogLevel != LogError {
+		log.Println(v...)
+	}
+}
+
+// splitList splits a comma separated list, dropping empty entries.
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gopackage/slack/bot"
+)
+
+func TestLoadConfigFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{"empty", "", ""},
+		{"valid", "log_level: debug\nrate_limit:\n  burst: 2\n", ""},
+		{"unknown key", "log_levle: debug\n", "field log_levle not found"},
+		{"unknown nested key", "rate_limit:\n  messages_per_secnd: 1\n", "field messages_per_secnd not found"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "bitbot.yaml")
+		if err := ioutil.WriteFile(path, []byte(tt.yaml), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		_, err := loadConfig([]string{"-config", path}, os.Stderr)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%s: loadConfig = %v", tt.name, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("%s: loadConfig = %v, want error containing %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestEnabledPlugins(t *testing.T) {
+	tests := []struct {
+		name    string
+		plugins map[string]PluginConfig
+		want    []string
+	}{
+		{"none configured", nil, bot.Plugins()},
+		{"enabled", map[string]PluginConfig{"ping": {Enabled: true}}, []string{"ping"}},
+		{"disabled", map[string]PluginConfig{"ping": {Enabled: false}}, nil},
+		{"sorted", map[string]PluginConfig{"b": {Enabled: true}, "a": {Enabled: true}, "c": {}}, []string{"a", "b"}},
+	}
+	for _, tt := range tests {
+		c := &Config{Plugins: tt.plugins}
+		if got := c.EnabledPlugins(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: EnabledPlugins() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
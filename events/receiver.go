@@ -0,0 +1,208 @@
+// Package events implements a receiver for the Slack Events API, which
+// delivers events to the app by POSTing them to a public HTTPS endpoint.
+//
+// Events are passed to an rtm.Handler in the same Envelopes the rtm and
+// socketmode clients use, so handlers registered on an rtm.ServeMux work
+// unchanged.
+package events
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"runtime"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/gopackage/slack/api"
+	"github.com/gopackage/slack/rtm"
+)
+
+// MaxTimestampSkew is how far the X-Slack-Request-Timestamp of a request
+// may be from the current time. Older requests are rejected so captured
+// requests can't be replayed.
+const MaxTimestampSkew = 5 * time.Minute
+
+// MaxBodySize is the largest request body accepted.
+const MaxBodySize = 1 << 20
+
+// shutdownPollInterval is how often Shutdown checks whether the Receiver
+// has finished handling events.
+const shutdownPollInterval = 50 * time.Millisecond
+
+// ErrInvalidSignature is returned by Verify when a request wasn't signed
+// with the signing secret.
+var ErrInvalidSignature = errors.New("events: invalid request signature")
+
+// Receiver is an http.Handler for the Events API endpoint. It verifies the
+// request signature, answers URL verification challenges and passes each
+// event to the handler. Slack expects an answer within three seconds, so
+// the request is acknowledged before the handler is called; handlers reply
+// through the Web API.
+//
+// Slack retries events that weren't acknowledged in time, so wrap the
+// handler with rtm.DedupHandler, which identifies events by their event ID.
+type Receiver struct {
+	secret  []byte
+	handler rtm.Handler
+	writer  *rtm.APIWriter
+	logger  *log.Logger
+
+	inShutdown int32 // accessed atomically (non-zero means we're in Shutdown)
+	inFlight   int64 // accessed atomically, handlers currently running
+}
+
+// Option configures a Receiver.
+type Option func(*Receiver)
+
+// NewReceiver creates a receiver that checks requests are signed with the
+// app's signing secret and passes events to h. Handlers reply by posting
+// with client, which should use the app's bot token.
+func NewReceiver(signingSecret string, client *api.Client, h rtm.Handler, opts ...Option) *Receiver {
+	r := &Receiver{
+		secret:  []byte(signingSecret),
+		handler: h,
+		writer:  rtm.NewAPIWriter(client),
+		logger:  log.New(os.Stderr, "", log.LstdFlags),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	r.writer.Logger = r.logger
+	return r
+}
+
+// WithLogger sets the logger the receiver reports handler panics and
+// handler errors to.
+func WithLogger(logger *log.Logger) Option {
+	return func(r *Receiver) {
+		r.logger = logger
+	}
+}
+
+// ServeHTTP handles a request from the Events API.
+func (r *Receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	// Count the request as in flight before checking for shutdown so
+	// Shutdown can't miss an event that got past the check. It stops
+	// being in flight when it returns unless an event is dispatched.
+	atomic.AddInt64(&r.inFlight, 1)
+	dispatched := false
+	defer func() {
+		if !dispatched {
+			atomic.AddInt64(&r.inFlight, -1)
+		}
+	}()
+	if atomic.LoadInt32(&r.inShutdown) != 0 {
+		// Slack retries the event later, hopefully on another instance.
+		http.Error(w, "shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(req.Body, MaxBodySize))
+	if err != nil {
+		http.Error(w, "reading request failed", http.StatusBadRequest)
+		return
+	}
+	err = Verify(r.secret, req.Header, body, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var payload struct {
+		Type      string `json:"type"`
+		Challenge string `json:"challenge"`
+	}
+	err = json.Unmarshal(body, &payload)
+	if err != nil {
+		http.Error(w, "invalid JSON", http.StatusBadRequest)
+		return
+	}
+	switch payload.Type {
+	case "url_verification":
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, payload.Challenge)
+	case "event_callback":
+		event, err := rtm.NewCallbackEnvelope(body, rtm.TransportEventsAPI)
+		if err != nil {
+			http.Error(w, "invalid event", http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		dispatched = true
+		go r.dispatch(event)
+	default:
+		// Acknowledge callbacks we don't understand, such as
+		// app_rate_limited, so Slack doesn't retry them.
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// dispatch passes the event to the handler. Handler panics are recovered
+// and logged.
+func (r *Receiver) dispatch(event *rtm.Envelope) {
+	defer atomic.AddInt64(&r.inFlight, -1)
+	defer func() {
+		if err := recover(); err != nil {
+			buf := make([]byte, 64<<10)
+			buf = buf[:runtime.Stack(buf, false)]
+			r.logger.Printf("events: panic handling event %s: %v\n%s", event.Raw, err, buf)
+		}
+	}()
+	r.handler.HandleEvent(r.writer, event)
+}
+
+// Shutdown stops the receiver accepting events, which makes Slack retry
+// them, and waits for running handlers to return or the context to
+// expire. The http.Server serving the receiver should be shut down
+// afterwards.
+func (r *Receiver) Shutdown(ctx context.Context) error {
+	atomic.StoreInt32(&r.inShutdown, 1)
+
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
+	for atomic.LoadInt64(&r.inFlight) != 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// Verify checks that a request with the header and body was signed by
+// Slack with the signing secret no longer than MaxTimestampSkew before
+// now. It returns ErrInvalidSignature if not.
+func Verify(secret []byte, header http.Header, body []byte, now time.Time) error {
+	ts := header.Get("X-Slack-Request-Timestamp")
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	skew := now.Sub(time.Unix(sec, 0))
+	if skew > MaxTimestampSkew || skew < -MaxTimestampSkew {
+		return ErrInvalidSignature
+	}
+	mac := hmac.New(sha256.New, secret)
+	io.WriteString(mac, "v0:"+ts+":")
+	mac.Write(body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(header.Get("X-Slack-Signature"))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
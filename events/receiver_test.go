@@ -0,0 +1,169 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gopackage/slack/api"
+	"github.com/gopackage/slack/rtm"
+)
+
+const secret = "8f742231b10e8888abcd99yyyzzz85a5"
+
+// signedHeader returns the headers Slack sends with body at time ts.
+func signedHeader(key, body string, ts time.Time) http.Header {
+	sec := strconv.FormatInt(ts.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte("v0:" + sec + ":" + body))
+	h := http.Header{}
+	h.Set("X-Slack-Request-Timestamp", sec)
+	h.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+	return h
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Now()
+	body := `{"type":"event_callback"}`
+	tests := []struct {
+		name   string
+		header http.Header
+		body   string
+		want   error
+	}{
+		{"valid", signedHeader(secret, body, now), body, nil},
+		{"slightly old", signedHeader(secret, body, now.Add(-time.Minute)), body, nil},
+		{"wrong secret", signedHeader("other", body, now), body, ErrInvalidSignature},
+		{"modified body", signedHeader(secret, body, now), body + " ", ErrInvalidSignature},
+		{"too old", signedHeader(secret, body, now.Add(-MaxTimestampSkew-time.Minute)), body, ErrInvalidSignature},
+		{"too new", signedHeader(secret, body, now.Add(MaxTimestampSkew+time.Minute)), body, ErrInvalidSignature},
+		{"unsigned", http.Header{}, body, ErrInvalidSignature},
+	}
+	for _, tt := range tests {
+		if got := Verify([]byte(secret), tt.header, []byte(tt.body), now); got != tt.want {
+			t.Errorf("%s: Verify = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// post sends a signed request to the receiver.
+func post(r *Receiver, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/slack/events", strings.NewReader(body))
+	for k, v := range signedHeader(secret, body, time.Now()) {
+		req.Header[k] = v
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+const messageEvent = `{"type":"event_callback","event_id":"Ev1","event":{"type":"message","channel":"C1","text":"hi"}}`
+
+func TestReceiver(t *testing.T) {
+	events := make(chan *rtm.Envelope, 1)
+	r := NewReceiver(secret, api.NewClient("xoxb-test"), rtm.HandlerFunc(func(w rtm.ResponseWriter, e *rtm.Envelope) {
+		events <- e
+	}))
+
+	w := post(r, `{"type":"url_verification","challenge":"3eZbrw1aBm2rZgRNFdxV2595E9CY3gmdALWMmHkvFXO7tYXAYM8P"}`)
+	if w.Code != http.StatusOK || w.Body.String() != "3eZbrw1aBm2rZgRNFdxV2595E9CY3gmdALWMmHkvFXO7tYXAYM8P" {
+		t.Errorf("url_verification = %d %q", w.Code, w.Body)
+	}
+
+	w = post(r, messageEvent)
+	if w.Code != http.StatusOK {
+		t.Fatalf("event_callback = %d", w.Code)
+	}
+	select {
+	case e := <-events:
+		if e.Type != rtm.EventMessage || e.Transport != rtm.TransportEventsAPI {
+			t.Errorf("got %s event over %s, want message over the Events API", e.Type, e.Transport)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler wasn't called")
+	}
+
+	req := httptest.NewRequest("POST", "/slack/events", strings.NewReader(messageEvent))
+	req.Header = signedHeader("other", messageEvent, time.Now())
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("badly signed request = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/slack/events", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET = %d, want %d", w.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestReceiverShutdown(t *testing.T) {
+	handling, release := make(chan struct{}), make(chan struct{})
+	r := NewReceiver(secret, api.NewClient("xoxb-test"), rtm.HandlerFunc(func(w rtm.ResponseWriter, e *rtm.Envelope) {
+		close(handling)
+		<-release
+	}))
+	if w := post(r, messageEvent); w.Code != http.StatusOK {
+		t.Fatalf("event_callback = %d", w.Code)
+	}
+	<-handling
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := r.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Shutdown with a running handler = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if w := post(r, messageEvent); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("event during shutdown = %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+	close(release)
+	if err := r.Shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown = %v", err)
+	}
+}
+
+func TestReceiverLogger(t *testing.T) {
+	var buf bytes.Buffer
+	var mu sync.Mutex
+	logged := make(chan struct{}, 2)
+	logger := log.New(writerFunc(func(p []byte) (int, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		buf.Write(p)
+		logged <- struct{}{}
+		return len(p), nil
+	}), "", 0)
+	mux := rtm.NewServeMux()
+	mux.HandleMessageFunc(func(rtm.ResponseWriter, *rtm.MessageEvent) { panic("boom") })
+	r := NewReceiver(secret, api.NewClient("xoxb-test"), mux, WithLogger(logger))
+
+	post(r, `{"type":"event_callback","event":{"type":"message","text":1}}`)
+	post(r, messageEvent)
+	for i := 0; i < 2; i++ {
+		select {
+		case <-logged:
+		case <-time.After(5 * time.Second):
+			t.Fatal("nothing logged")
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !strings.Contains(buf.String(), "failed to decode message event") || !strings.Contains(buf.String(), "panic handling event") {
+		t.Errorf("log = %q, want the decode failure and the panic", buf.String())
+	}
+}
+
+type writerFunc func([]byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
@@ -0,0 +1,9 @@
+module github.com/gopackage/slack
+
+go 1.21
+
+require (
+	golang.org/x/net v0.30.0
+	golang.org/x/sync v0.9.0
+	gopkg.in/yaml.v3 v3.0.1
+)
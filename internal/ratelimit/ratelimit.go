@@ -0,0 +1,105 @@
+// Package ratelimit implements the token bucket used to pace requests to
+// Slack.
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter is a token bucket that allows bursts of up to burst events and
+// refills at rate events per second. Limiters are safe for concurrent use.
+type Limiter struct {
+	mu          sync.Mutex
+	rate        float64
+	burst       float64
+	tokens      float64
+	last        time.Time
+	pausedUntil time.Time
+}
+
+// New creates a limiter allowing rate events per second with bursts of up
+// to burst events. The bucket starts full. A burst less than one is
+// treated as one. A rate of zero or less allows any number of events,
+// although the limiter can still be paused.
+func New(rate float64, burst int) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limiter{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// Wait blocks until an event is allowed or the context is done.
+func (l *Limiter) Wait(ctx context.Context) error {
+	delay := l.reserve()
+	for delay > 0 {
+		t := time.NewTimer(delay)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			// Give back the token we won't use.
+			l.mu.Lock()
+			l.tokens++
+			l.mu.Unlock()
+			return ctx.Err()
+		}
+		// A Pause while we were waiting holds back this event too.
+		delay = l.paused()
+	}
+	return nil
+}
+
+// reserve takes a token and returns how long to wait before using it.
+func (l *Limiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.refill()
+	l.tokens--
+	var delay time.Duration
+	if l.tokens < 0 && l.rate > 0 {
+		delay = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	if pause := l.pausedUntil.Sub(now); pause > delay {
+		delay = pause
+	}
+	return delay
+}
+
+// Pause holds back events for at least d, including events already
+// waiting, e.g. after the server asked the caller to slow down.
+func (l *Limiter) Pause(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.refill()
+	if until := now.Add(d); until.After(l.pausedUntil) {
+		l.pausedUntil = until
+	}
+	// Tokens that would build up during the pause are not allowed, so
+	// events resume at the normal rate rather than in a burst.
+	if paused := 1 - d.Seconds()*l.rate; l.rate > 0 && l.tokens > paused {
+		l.tokens = paused
+	}
+}
+
+// paused returns how much longer the limiter is paused.
+func (l *Limiter) paused() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return time.Until(l.pausedUntil)
+}
+
+// refill adds the tokens earned since the last update and returns the
+// current time. l.mu must be held.
+func (l *Limiter) refill() time.Time {
+	now := time.Now()
+	if l.rate > 0 {
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	}
+	l.last = now
+	return now
+}
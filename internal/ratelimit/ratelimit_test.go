@@ -0,0 +1,89 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// slack allows for the time that passes while a test runs.
+const slack = 20 * time.Millisecond
+
+func near(got, want time.Duration) bool {
+	return got >= want-slack && got <= want+slack
+}
+
+func TestReserve(t *testing.T) {
+	tests := []struct {
+		name  string
+		rate  float64
+		burst int
+		n     int           // events reserved before the one checked
+		pause time.Duration // paused before reserving
+		want  time.Duration // delay of the checked event
+	}{
+		{name: "within burst", rate: 1, burst: 3, n: 2, want: 0},
+		{name: "first over burst", rate: 1, burst: 3, n: 3, want: time.Second},
+		{name: "second over burst", rate: 2, burst: 1, n: 2, want: time.Second},
+		{name: "zero burst is one", rate: 10, burst: 0, n: 1, want: 100 * time.Millisecond},
+		{name: "unlimited", rate: 0, burst: 1, n: 100, want: 0},
+		{name: "paused", rate: 1, burst: 5, pause: 2 * time.Second, want: 2 * time.Second},
+		{name: "paused drains burst", rate: 1, burst: 5, n: 1, pause: 2 * time.Second, want: 3 * time.Second},
+		{name: "paused longer than refill", rate: 10, burst: 1, pause: time.Second, want: time.Second},
+		{name: "paused unlimited", rate: 0, burst: 1, n: 3, pause: time.Second, want: time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := New(tt.rate, tt.burst)
+			if tt.pause > 0 {
+				l.Pause(tt.pause)
+			}
+			for i := 0; i < tt.n; i++ {
+				l.reserve()
+			}
+			if got := l.reserve(); !near(got, tt.want) {
+				t.Errorf("delay = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPauseKeepsLongerPause(t *testing.T) {
+	l := New(0, 1)
+	l.Pause(time.Second)
+	l.Pause(100 * time.Millisecond)
+	if got := l.paused(); !near(got, time.Second) {
+		t.Errorf("paused = %v, want %v", got, time.Second)
+	}
+}
+
+func TestWaitHonoursPauseWhileWaiting(t *testing.T) {
+	l := New(20, 1)
+	l.reserve()
+	start := time.Now()
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		l.Pause(200 * time.Millisecond)
+	}()
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := time.Since(start); got < 200*time.Millisecond {
+		t.Errorf("Wait returned after %v, before the pause ended", got)
+	}
+}
+
+func TestWaitCancelReturnsToken(t *testing.T) {
+	l := New(1, 1)
+	l.reserve()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := l.Wait(ctx); err != context.Canceled {
+		t.Fatalf("Wait = %v, want %v", err, context.Canceled)
+	}
+	// Only the first reservation is still held, so the next event waits
+	// one interval rather than two.
+	if got := l.reserve(); !near(got, time.Second) {
+		t.Errorf("delay = %v, want %v", got, time.Second)
+	}
+}
@@ -0,0 +1,57 @@
+// Package ping is a bot plugin that answers "!ping" with "pong" so you can
+// check the bot is alive. Import it for its side effect of registering the
+// plugin:
+//
+//	import _ "github.com/gopackage/slack/plugins/ping"
+package ping
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gopackage/slack/bot"
+	"github.com/gopackage/slack/rtm"
+)
+
+func init() {
+	bot.Register(&plugin{})
+}
+
+// plugin replies to the configured command.
+type plugin struct {
+	command string
+	reply   string
+}
+
+// Name identifies the plugin.
+func (p *plugin) Name() string {
+	return "ping"
+}
+
+// Init reads the "command" and "reply" settings and registers the message
+// handler.
+func (p *plugin) Init(b *bot.Bot) error {
+	config := b.Config(p.Name())
+	p.command = config.Get("command", "!ping")
+	p.reply = config.Get("reply", "pong")
+	b.Mux().HandleMessageFunc(p.handleMessage)
+	return nil
+}
+
+// Start does nothing as the plugin has no background work.
+func (p *plugin) Start(ctx context.Context) error {
+	return nil
+}
+
+// Stop does nothing as the plugin has no background work.
+func (p *plugin) Stop(ctx context.Context) error {
+	return nil
+}
+
+// handleMessage replies to the command.
+func (p *plugin) handleMessage(w rtm.ResponseWriter, m *rtm.MessageEvent) {
+	if m.Subtype != "" || strings.TrimSpace(m.Text) != p.command {
+		return
+	}
+	w.WriteMsg(m.Channel, p.reply)
+}
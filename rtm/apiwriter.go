@@ -0,0 +1,91 @@
+package rtm
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/url"
+	"reflect"
+
+	"github.com/gopackage/slack/api"
+	"github.com/gopackage/slack/types"
+)
+
+// ErrNotSupported is returned by a ResponseWriter for writes its transport
+// can't make, such as typing indicators outside RTM.
+var ErrNotSupported = errors.New("rtm: not supported by this transport")
+
+// APIWriter is a ResponseWriter that replies through the Web API. It is
+// used by transports that have no RTM connection to write to, such as
+// Socket Mode and the Events API.
+type APIWriter struct {
+	// Logger is where handlers report errors. Nil uses the log package.
+	Logger *log.Logger
+
+	api *api.Client
+}
+
+// NewAPIWriter creates a ResponseWriter posting replies with client.
+func NewAPIWriter(client *api.Client) *APIWriter {
+	return &APIWriter{api: client}
+}
+
+// Write posts msg with chat.postMessage. Only messages of type "message"
+// can be written; the other fields, such as "channel", "text" and
+// "thread_ts", are passed as arguments: strings (including IDs and
+// timestamps) as they are and everything else, such as "blocks", JSON
+// encoded as the Web API expects. The number of bytes of arguments
+// sent is returned.
+func (w *APIWriter) Write(msg map[string]interface{}) (int, error) {
+	if msg["type"] != EventMessage {
+		return 0, ErrNotSupported
+	}
+	args := url.Values{}
+	for k, v := range msg {
+		if k == "type" || k == "id" {
+			continue
+		}
+		arg, err := apiArg(v)
+		if err != nil {
+			return -1, err
+		}
+		args.Set(k, arg)
+	}
+	err := w.api.Call("chat.postMessage", args, nil)
+	if err != nil {
+		return -1, err
+	}
+	return len(args.Encode()), nil
+}
+
+// apiArg encodes a message field as a Web API argument.
+func apiArg(v interface{}) (string, error) {
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.String {
+		return rv.String(), nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// WriteMsg posts a text message to the channel.
+func (w *APIWriter) WriteMsg(channel types.ChannelID, text string) (int, error) {
+	_, err := w.api.PostMessage(channel, text)
+	if err != nil {
+		return -1, err
+	}
+	return len(text), nil
+}
+
+// ErrorLog returns w.Logger. It implements ErrorLogger.
+func (w *APIWriter) ErrorLog() *log.Logger {
+	return w.Logger
+}
+
+// WriteTyping returns ErrNotSupported since the Web API has no typing
+// indicator.
+func (w *APIWriter) WriteTyping(channel types.ChannelID) (int, error) {
+	return 0, ErrNotSupported
+}
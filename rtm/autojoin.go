@@ -0,0 +1,37 @@
+package rtm
+
+import "regexp"
+
+// AutoJoin configures which channels a client joins by itself. See
+// WithAutoJoin.
+//
+// There is no setting for invitations because a bot doesn't have to
+// accept them: inviting a bot to a channel makes it a member straight
+// away, and the client receives a member_joined_channel event for itself.
+type AutoJoin struct {
+	// Channels joins newly created public channels whose name matches the
+	// pattern. Nil disables joining new channels.
+	Channels *regexp.Regexp
+}
+
+// autoJoinEvent joins channels announced by the event if the client is
+// configured to. The Web API call runs in its own goroutine so it doesn't
+// hold up reading.
+func (c *Client) autoJoinEvent(event *Envelope) {
+	if c.autoJoin == nil || c.autoJoin.Channels == nil || event.Type != EventChannelCreated {
+		return
+	}
+	var e ChannelCreatedEvent
+	if event.Decode(&e) != nil || !c.autoJoin.Channels.MatchString(e.Channel.Name) {
+		return
+	}
+	channel := e.Channel.ID
+	go func() {
+		_, err := c.api.JoinConversation(channel)
+		if err != nil {
+			c.logger.Println("rtm.autojoin failed to join", channel, err)
+			return
+		}
+		c.debug.Println("rtm.autojoin joined", channel)
+	}()
+}
@@ -0,0 +1,142 @@
+package rtm
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+
+	"github.com/gopackage/slack/api"
+	"github.com/gopackage/slack/types"
+)
+
+// DefaultMaxReplay is the maximum number of messages replayed per channel
+// by a CatchUp handler when MaxReplay is not set.
+const DefaultMaxReplay = 1000
+
+// HistoryFetcher fetches the message history of a channel. It is satisfied
+// by *api.Client.
+type HistoryFetcher interface {
+	ConversationHistory(channel types.ChannelID, params api.HistoryParams) (*api.HistoryResponse, error)
+}
+
+// CatchUp is a Handler that recovers messages missed while the RTM
+// connection was down. It records the timestamp of the last message seen
+// on each channel and, when the "hello" event arrives on a new connection,
+// fetches newer messages from conversations.history and replays them
+// through the wrapped handler before any live events are handled.
+//
+// Replayed events are marked so handlers can tell them apart from live
+// events using Envelope.Replayed. Wrap a DedupHandler with CatchUp (not the
+// other way around) so messages that are both replayed and redelivered by
+// Slack are only handled once.
+type CatchUp struct {
+	// MaxReplay limits the number of messages replayed per channel. Older
+	// missed messages are skipped. Zero uses DefaultMaxReplay.
+	MaxReplay int
+
+	handler Handler
+	history HistoryFetcher
+
+	mu   sync.Mutex
+	last map[types.ChannelID]types.Timestamp
+}
+
+// CatchUpHandler returns a CatchUp handler that replays missed messages
+// fetched with history through h.
+func CatchUpHandler(h Handler, history HistoryFetcher) *CatchUp {
+	return &CatchUp{handler: h, history: history, last: make(map[types.ChannelID]types.Timestamp)}
+}
+
+// HandleEvent records message timestamps, replays missed messages on hello
+// and passes all events on to the wrapped handler.
+func (c *CatchUp) HandleEvent(resp ResponseWriter, event *Envelope) {
+	switch event.Type {
+	case EventHello:
+		c.handler.HandleEvent(resp, event)
+		c.replay(resp)
+		return
+	case EventMessage:
+		var m struct {
+			Channel types.ChannelID `json:"channel"`
+			TS      types.Timestamp `json:"ts"`
+		}
+		if event.Decode(&m) == nil {
+			c.seen(m.Channel, m.TS)
+		}
+	}
+	c.handler.HandleEvent(resp, event)
+}
+
+// seen records ts as the last message timestamp on channel if it is newer
+// than the one already recorded.
+func (c *CatchUp) seen(channel types.ChannelID, ts types.Timestamp) {
+	if channel == "" || ts == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ts.After(c.last[channel]) {
+		c.last[channel] = ts
+	}
+}
+
+// replay fetches and replays the messages missed on every channel that
+// has been seen so far.
+func (c *CatchUp) replay(resp ResponseWriter) {
+	c.mu.Lock()
+	last := make(map[types.ChannelID]types.Timestamp, len(c.last))
+	for channel, ts := range c.last {
+		last[channel] = ts
+	}
+	c.mu.Unlock()
+
+	for channel, ts := range last {
+		missed, err := c.missed(channel, ts)
+		if err != nil {
+			log.Println("rtm.catchup failed to fetch history", channel, err)
+		}
+		for _, msg := range missed {
+			msg.Channel = channel
+			data, err := json.Marshal(&MessageEvent{Message: msg, Replayed: true})
+			if err != nil {
+				continue
+			}
+			c.seen(channel, msg.TS)
+			c.handler.HandleEvent(resp, &Envelope{Type: EventMessage, TS: msg.TS, Raw: data, Replayed: true})
+		}
+	}
+}
+
+// missed pages through the channel history after ts and returns the
+// messages in the order they were posted.
+func (c *CatchUp) missed(channel types.ChannelID, ts types.Timestamp) ([]types.Message, error) {
+	max := c.MaxReplay
+	if max <= 0 {
+		max = DefaultMaxReplay
+	}
+	var messages []types.Message
+	params := api.HistoryParams{Oldest: ts, Limit: 200}
+	for len(messages) < max {
+		r, err := c.history.ConversationHistory(channel, params)
+		if err != nil {
+			return reverse(messages), err
+		}
+		messages = append(messages, r.Messages...)
+		if !r.HasMore || r.Metadata.NextCursor == "" {
+			break
+		}
+		params.Cursor = r.Metadata.NextCursor
+	}
+	if len(messages) > max {
+		messages = messages[:max]
+	}
+	return reverse(messages), nil
+}
+
+// reverse puts history (newest first) into the order it was posted.
+func reverse(messages []types.Message) []types.Message {
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+	return messages
+}
@@ -0,0 +1,112 @@
+package rtm
+
+import (
+	"container/list"
+	"encoding/json"
+	"sync"
+)
+
+// DefaultDedupSize is the number of event keys remembered by the LRU store
+// created by DedupHandler when no store is provided.
+const DefaultDedupSize = 1024
+
+// DedupStore records the keys of events that have already been handled.
+// Implementations must be safe for concurrent use. Deployments running
+// several bot instances against the same workspace can share a store
+// (e.g. backed by Redis or memcached) so only one instance handles each event.
+type DedupStore interface {
+	// Seen records the key and reports whether it had already been recorded.
+	Seen(key string) bool
+}
+
+// LRUDedupStore is an in-memory DedupStore that remembers a bounded number
+// of the most recently seen event keys.
+type LRUDedupStore struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List
+	keys  map[string]*list.Element
+}
+
+// NewLRUDedupStore creates a store that remembers up to size event keys.
+// A size less than one uses DefaultDedupSize.
+func NewLRUDedupStore(size int) *LRUDedupStore {
+	if size < 1 {
+		size = DefaultDedupSize
+	}
+	return &LRUDedupStore{size: size, order: list.New(), keys: make(map[string]*list.Element)}
+}
+
+// Seen records the key and reports whether it had already been recorded.
+// When the store is full the least recently seen key is forgotten.
+func (s *LRUDedupStore) Seen(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.keys[key]; ok {
+		s.order.MoveToFront(e)
+		return true
+	}
+	s.keys[key] = s.order.PushFront(key)
+	if s.order.Len() > s.size {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.keys, oldest.Value.(string))
+	}
+	return false
+}
+
+// dedupHandler drops events that have already been passed to the wrapped
+// handler.
+type dedupHandler struct {
+	handler Handler
+	store   DedupStore
+}
+
+// DedupHandler returns a Handler that passes each event to h at most once.
+// Slack may redeliver recent events after a reconnect and the Events API
+// retries deliveries that were not acknowledged in time, so events are
+// identified by their event_id when present or by their type, channel and
+// timestamp otherwise. Events that carry no identity (hello, pong, replies)
+// are always passed through. A nil store uses an LRU store of
+// DefaultDedupSize keys.
+func DedupHandler(h Handler, store DedupStore) Handler {
+	if store == nil {
+		store = NewLRUDedupStore(DefaultDedupSize)
+	}
+	return &dedupHandler{handler: h, store: store}
+}
+
+// HandleEvent passes the event to the wrapped handler unless it is a duplicate.
+func (d *dedupHandler) HandleEvent(resp ResponseWriter, event *Envelope) {
+	if key := dedupKey(event); key != "" && d.store.Seen(key) {
+		return
+	}
+	d.handler.HandleEvent(resp, event)
+}
+
+// dedupKey determines the identity of an event or returns an empty string
+// if the event can't be identified.
+func dedupKey(event *Envelope) string {
+	var ids struct {
+		EventID string `json:"event_id"`
+		// Channel is an ID for most events but an object for some, such
+		// as channel_created, so it's only used if it's a string.
+		Channel json.RawMessage `json:"channel"`
+	}
+	if event.EventID != "" {
+		return event.EventID
+	}
+	if event.Decode(&ids) != nil {
+		return ""
+	}
+	if ids.EventID != "" {
+		return ids.EventID
+	}
+	if event.TS == "" {
+		return ""
+	}
+	var channel string
+	json.Unmarshal(ids.Channel, &channel)
+	return event.Type + "/" + channel + "/" + event.TS.String()
+}
@@ -0,0 +1,134 @@
+package rtm
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/gopackage/slack/types"
+)
+
+// Transports events are received over, reported by Envelope.Transport.
+const (
+	TransportRTM        = "rtm"
+	TransportSocketMode = "socket_mode"
+	TransportEventsAPI  = "events_api"
+)
+
+// Envelope is an event received from Slack. Only the fields needed to
+// route the event are decoded up front; handlers decode the payload they
+// are interested in from Raw with Decode. This avoids decoding every event
+// on busy workspaces and gives handlers access to the exact bytes sent by
+// Slack.
+//
+// Envelopes are the same whichever transport delivered the event: for the
+// Events API and Socket Mode, Raw is the inner event of the event_callback
+// so handlers registered on a ServeMux work unchanged with any transport.
+type Envelope struct {
+	// Type of the event e.g. "message" or "reaction_added"
+	Type string
+	// Transport is how the event was received, one of TransportRTM,
+	// TransportSocketMode or TransportEventsAPI. It is empty for events
+	// made up by handlers such as CatchUp.
+	Transport string
+	// EventID uniquely identifies events delivered by the Events API and
+	// Socket Mode, which both redeliver events that weren't acknowledged
+	// in time. It is empty for RTM events.
+	EventID string
+	// TeamID is the workspace the event belongs to, if the transport
+	// reports it.
+	TeamID types.TeamID
+	// TS is the timestamp of the event (event_ts, or ts for events without
+	// an event_ts). TS is empty for events that aren't timestamped such as
+	// hello and pong.
+	TS types.Timestamp
+	// Raw is the JSON encoded event.
+	Raw json.RawMessage
+	// Replayed is true if the event was missed while disconnected and
+	// replayed by a CatchUp handler rather than received live.
+	Replayed bool
+
+	// replyTo is the id of the sent message this event replies to, if any.
+	replyTo *int64
+	// ok and err are set on replies to indicate success or failure.
+	ok  *bool
+	err string
+}
+
+// envelopeHeader contains the fields decoded to build an Envelope.
+type envelopeHeader struct {
+	Type    string          `json:"type"`
+	TS      types.Timestamp `json:"ts"`
+	EventTS types.Timestamp `json:"event_ts"`
+	ReplyTo *int64          `json:"reply_to"`
+	Ok      *bool           `json:"ok"`
+	// Error is an object with a msg field on RTM replies but a plain
+	// string elsewhere.
+	Error json.RawMessage `json:"error"`
+}
+
+// errorMsg extracts the message from an error field.
+func errorMsg(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var e struct {
+		Msg string `json:"msg"`
+	}
+	if json.Unmarshal(raw, &e) == nil {
+		return e.Msg
+	}
+	var msg string
+	json.Unmarshal(raw, &msg)
+	return msg
+}
+
+// NewEnvelope wraps the raw JSON encoded event in an Envelope. The raw
+// bytes are retained, not copied.
+func NewEnvelope(raw []byte) (*Envelope, error) {
+	var h envelopeHeader
+	err := json.Unmarshal(raw, &h)
+	if err != nil {
+		return nil, err
+	}
+	ts := h.EventTS
+	if ts == "" {
+		ts = h.TS
+	}
+	return &Envelope{Type: h.Type, TS: ts, Raw: raw, replyTo: h.ReplyTo, ok: h.Ok, err: errorMsg(h.Error)}, nil
+}
+
+// callback is an Events API event_callback payload.
+type callback struct {
+	Type    string          `json:"type"`
+	TeamID  types.TeamID    `json:"team_id"`
+	EventID string          `json:"event_id"`
+	Event   json.RawMessage `json:"event"`
+}
+
+// NewCallbackEnvelope unwraps the event from an Events API event_callback
+// payload, as posted by the Events API or carried by Socket Mode, and
+// wraps it in an Envelope for the transport.
+func NewCallbackEnvelope(payload []byte, transport string) (*Envelope, error) {
+	var cb callback
+	err := json.Unmarshal(payload, &cb)
+	if err != nil {
+		return nil, err
+	}
+	if cb.Type != "event_callback" || len(cb.Event) == 0 {
+		return nil, fmt.Errorf("rtm: unexpected callback type %q", cb.Type)
+	}
+	e, err := NewEnvelope(cb.Event)
+	if err != nil {
+		return nil, err
+	}
+	e.Transport = transport
+	e.EventID = cb.EventID
+	e.TeamID = cb.TeamID
+	return e, nil
+}
+
+// Decode decodes the event payload into v, typically a pointer to one of
+// the event structs such as *MessageEvent.
+func (e *Envelope) Decode(v interface{}) error {
+	return json.Unmarshal(e.Raw, v)
+}
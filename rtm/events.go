@@ -0,0 +1,208 @@
+package rtm
+
+import (
+	"github.com/gopackage/slack/types"
+)
+
+// RTM event types. Use these constants when registering handlers so typos
+// are caught by the compiler instead of silently matching nothing.
+const (
+	// EventHello is received when the connection is ready to use.
+	EventHello = "hello"
+	// EventGoodbye is received when the server is about to close the
+	// connection.
+	EventGoodbye = "goodbye"
+	// EventPing is sent by clients to keep the connection alive.
+	EventPing = "ping"
+	// EventPong is received in reply to a ping.
+	EventPong = "pong"
+	// EventMessage is received when a message is posted and sent to post
+	// a message.
+	EventMessage = "message"
+	// EventTyping is sent to show the typing indicator in a channel.
+	EventTyping = "typing"
+	// EventUserTyping is received when a user is typing in a channel.
+	EventUserTyping = "user_typing"
+	// EventReactionAdded is received when a reaction is added to an item.
+	EventReactionAdded = "reaction_added"
+	// EventReactionRemoved is received when a reaction is removed from an item.
+	EventReactionRemoved = "reaction_removed"
+	// EventChannelCreated is received when a channel is created.
+	EventChannelCreated = "channel_created"
+	// EventChannelJoined is received when the bot joins a channel.
+	EventChannelJoined = "channel_joined"
+	// EventChannelLeft is received when the bot leaves a channel.
+	EventChannelLeft = "channel_left"
+	// EventMemberJoinedChannel is received when a user joins a channel.
+	EventMemberJoinedChannel = "member_joined_channel"
+	// EventMemberLeftChannel is received when a user leaves a channel.
+	EventMemberLeftChannel = "member_left_channel"
+)
+
+// MessageEvent is received when a message is posted to a channel the bot
+// is a member of ("message").
+type MessageEvent struct {
+	types.Message
+	// EventTS is the timestamp of the event, which differs from the
+	// message timestamp for edits and deletions
+	EventTS types.Timestamp `json:"event_ts,omitempty"`
+	// Hidden is true for messages that clients don't display, such as
+	// edits and deletions
+	Hidden bool `json:"hidden,omitempty"`
+	// Replayed is true if the message was missed while disconnected and
+	// replayed by a CatchUp handler
+	Replayed bool `json:"replayed,omitempty"`
+}
+
+// ReactionItem identifies the item a reaction was added to or removed from.
+type ReactionItem struct {
+	// Type of the item: "message", "file" or "file_comment"
+	Type string `json:"type"`
+	// Channel the message is in (message items only)
+	Channel types.ChannelID `json:"channel,omitempty"`
+	// TS is the timestamp of the message (message items only)
+	TS types.Timestamp `json:"ts,omitempty"`
+	// File is the ID of the file (file and file_comment items only)
+	File string `json:"file,omitempty"`
+	// FileComment is the ID of the comment (file_comment items only)
+	FileComment string `json:"file_comment,omitempty"`
+}
+
+// ReactionEvent is received when a user adds ("reaction_added") or removes
+// ("reaction_removed") an emoji reaction.
+type ReactionEvent struct {
+	// Type is "reaction_added" or "reaction_removed"
+	Type string `json:"type"`
+	// User is the ID of the user that added or removed the reaction
+	User types.UserID `json:"user"`
+	// Reaction is the emoji name without colons e.g. "eyes" or
+	// "thumbsup::skin-tone-2"
+	Reaction string `json:"reaction"`
+	// ItemUser is the ID of the user that created the item, if known
+	ItemUser types.UserID `json:"item_user,omitempty"`
+	// Item the reaction applies to
+	Item ReactionItem `json:"item"`
+	// EventTS is the timestamp of the event
+	EventTS types.Timestamp `json:"event_ts"`
+}
+
+// ChannelCreatedEvent is received when a channel is created ("channel_created").
+type ChannelCreatedEvent struct {
+	// Type is always "channel_created"
+	Type string `json:"type"`
+	// Channel is the new channel. Only the ID, name, creation time and
+	// creator are set.
+	Channel types.Channel `json:"channel"`
+}
+
+// MemberJoinedChannelEvent is received when a user joins a channel
+// ("member_joined_channel").
+type MemberJoinedChannelEvent struct {
+	// Type is always "member_joined_channel"
+	Type string `json:"type"`
+	// User is the ID of the user that joined
+	User types.UserID `json:"user"`
+	// Channel is the ID of the channel joined
+	Channel types.ChannelID `json:"channel"`
+	// ChannelType is "C" for public channels and "G" for private channels
+	ChannelType string `json:"channel_type"`
+	// Team is the ID of the user's team
+	Team types.TeamID `json:"team"`
+	// Inviter is the ID of the user that invited the user, if any
+	Inviter types.UserID `json:"inviter,omitempty"`
+}
+
+// HandleMessageFunc registers a handler for message events on the mux.
+func (mux *ServeMux) HandleMessageFunc(handler func(resp ResponseWriter, m *MessageEvent)) {
+	mux.HandleFunc(EventMessage, func(resp ResponseWriter, event *Envelope) {
+		var e MessageEvent
+		if decodeTyped(resp, event, &e) {
+			handler(resp, &e)
+		}
+	})
+}
+
+// HandleReactionAddedFunc registers a handler for reaction_added events on
+// the mux. Use a ReactionMux to route reactions by emoji and channel.
+func (mux *ServeMux) HandleReactionAddedFunc(handler func(resp ResponseWriter, r *ReactionEvent)) {
+	mux.HandleFunc(EventReactionAdded, func(resp ResponseWriter, event *Envelope) {
+		var e ReactionEvent
+		if decodeTyped(resp, event, &e) {
+			handler(resp, &e)
+		}
+	})
+}
+
+// HandleReactionRemovedFunc registers a handler for reaction_removed events
+// on the mux.
+func (mux *ServeMux) HandleReactionRemovedFunc(handler func(resp ResponseWriter, r *ReactionEvent)) {
+	mux.HandleFunc(EventReactionRemoved, func(resp ResponseWriter, event *Envelope) {
+		var e ReactionEvent
+		if decodeTyped(resp, event, &e) {
+			handler(resp, &e)
+		}
+	})
+}
+
+// HandleChannelCreatedFunc registers a handler for channel_created events
+// on the mux.
+func (mux *ServeMux) HandleChannelCreatedFunc(handler func(resp ResponseWriter, c *ChannelCreatedEvent)) {
+	mux.HandleFunc(EventChannelCreated, func(resp ResponseWriter, event *Envelope) {
+		var e ChannelCreatedEvent
+		if decodeTyped(resp, event, &e) {
+			handler(resp, &e)
+		}
+	})
+}
+
+// HandleMemberJoinedChannelFunc registers a handler for
+// member_joined_channel events on the mux.
+func (mux *ServeMux) HandleMemberJoinedChannelFunc(handler func(resp ResponseWriter, m *MemberJoinedChannelEvent)) {
+	mux.HandleFunc(EventMemberJoinedChannel, func(resp ResponseWriter, event *Envelope) {
+		var e MemberJoinedChannelEvent
+		if decodeTyped(resp, event, &e) {
+			handler(resp, &e)
+		}
+	})
+}
+
+// HandleMessageFunc registers a handler for message events on the
+// DefaultServeMux.
+func HandleMessageFunc(handler func(resp ResponseWriter, m *MessageEvent)) {
+	DefaultServeMux.HandleMessageFunc(handler)
+}
+
+// HandleReactionAddedFunc registers a handler for reaction_added events on
+// the DefaultServeMux.
+func HandleReactionAddedFunc(handler func(resp ResponseWriter, r *ReactionEvent)) {
+	DefaultServeMux.HandleReactionAddedFunc(handler)
+}
+
+// HandleReactionRemovedFunc registers a handler for reaction_removed events
+// on the DefaultServeMux.
+func HandleReactionRemovedFunc(handler func(resp ResponseWriter, r *ReactionEvent)) {
+	DefaultServeMux.HandleReactionRemovedFunc(handler)
+}
+
+// HandleChannelCreatedFunc registers a handler for channel_created events
+// on the DefaultServeMux.
+func HandleChannelCreatedFunc(handler func(resp ResponseWriter, c *ChannelCreatedEvent)) {
+	DefaultServeMux.HandleChannelCreatedFunc(handler)
+}
+
+// HandleMemberJoinedChannelFunc registers a handler for
+// member_joined_channel events on the DefaultServeMux.
+func HandleMemberJoinedChannelFunc(handler func(resp ResponseWriter, m *MemberJoinedChannelEvent)) {
+	DefaultServeMux.HandleMemberJoinedChannelFunc(handler)
+}
+
+// decodeTyped decodes the event for a typed handler, logging events that
+// don't match the expected structure to resp's error log.
+func decodeTyped(resp ResponseWriter, event *Envelope, v interface{}) bool {
+	err := event.Decode(v)
+	if err != nil {
+		errorLog(resp).Printf("rtm: failed to decode %s event: %v", event.Type, err)
+		return false
+	}
+	return true
+}
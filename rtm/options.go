@@ -0,0 +1,184 @@
+package rtm
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gopackage/slack/api"
+	"github.com/gopackage/slack/internal/ratelimit"
+)
+
+const (
+	// DefaultOrigin is the Origin header sent when dialing the RTM websocket.
+	DefaultOrigin = "https://api.slack.com"
+	// DefaultPingInterval is how long the connection may be idle before the
+	// client sends a ping.
+	DefaultPingInterval = 25 * time.Second
+	// DefaultPongTimeout is how long the client waits for the server to
+	// answer a ping before giving up on the connection.
+	DefaultPongTimeout = 20 * time.Second
+	// DefaultWriteTimeout is how long a single write may take before it
+	// fails.
+	DefaultWriteTimeout = 10 * time.Second
+	// DefaultReadBufferSize is the largest inbound frame the client buffers.
+	DefaultReadBufferSize = 1 << 20
+	// DefaultTimeout bounds the rtm.start call and dialing the websocket.
+	DefaultTimeout = 30 * time.Second
+)
+
+// Option configures a Client created with NewClient.
+type Option func(*Client)
+
+// NewClient creates an RTM client that connects using the provided token.
+// The client is configured with the default settings, which may be
+// overridden with options.
+func NewClient(token string, options ...Option) *Client {
+	c := &Client{
+		token:          token,
+		origin:         DefaultOrigin,
+		pingInterval:   DefaultPingInterval,
+		pongTimeout:    DefaultPongTimeout,
+		writeTimeout:   DefaultWriteTimeout,
+		readBufferSize: DefaultReadBufferSize,
+		timeout:        DefaultTimeout,
+		logger:         log.New(os.Stderr, "", log.LstdFlags),
+	}
+	for _, option := range options {
+		option(c)
+	}
+	if c.debug == nil {
+		c.debug = c.logger
+	}
+	if c.httpClient == nil {
+		c.httpClient = &http.Client{Timeout: c.timeout}
+	}
+	if c.api == nil {
+		c.api = api.NewClient(token)
+	}
+	return c
+}
+
+// WithOrigin sets the Origin header sent when dialing the RTM websocket.
+func WithOrigin(origin string) Option {
+	return func(c *Client) {
+		c.origin = origin
+	}
+}
+
+// WithPingInterval sets how long the connection may be idle before the
+// client sends a ping to keep it alive.
+func WithPingInterval(d time.Duration) Option {
+	return func(c *Client) {
+		c.pingInterval = d
+	}
+}
+
+// WithPongTimeout sets how long the client waits for any frame from the
+// server after sending a ping. If nothing arrives in time the connection
+// is closed and DialAndListen returns ErrPongTimeout. Slow or flaky
+// networks may need a longer timeout. Zero disables the timeout.
+func WithPongTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.pongTimeout = d
+	}
+}
+
+// WithWriteTimeout sets the deadline for each write to the connection. A
+// write that doesn't complete in time fails. Zero disables write deadlines.
+func WithWriteTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.writeTimeout = d
+	}
+}
+
+// WithReadBufferSize sets the size in bytes of the largest inbound frame
+// the client will buffer. Reading a larger frame fails the connection.
+func WithReadBufferSize(size int) Option {
+	return func(c *Client) {
+		c.readBufferSize = size
+	}
+}
+
+// WithTimeout bounds the time taken by the rtm.start call and dialing the
+// websocket.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = d
+	}
+}
+
+// WithHTTPClient sets the HTTP client used for the rtm.start call. The
+// timeout set by WithTimeout is not applied to a client set this way.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = client
+	}
+}
+
+// WithAPIClient sets the Web API client used by helpers such as DM that
+// need Web API methods. The default is a client using the same token with
+// the default settings.
+func WithAPIClient(client *api.Client) Option {
+	return func(c *Client) {
+		c.api = client
+	}
+}
+
+// WithLogger sets the logger the client reports errors and handler panics
+// to. The default logs to standard error.
+func WithLogger(logger *log.Logger) Option {
+	return func(c *Client) {
+		c.logger = logger
+	}
+}
+
+// WithDebugLogger sets the logger the client reports each connection step
+// and every frame it reads and writes to. The default is the logger set
+// with WithLogger.
+func WithDebugLogger(logger *log.Logger) Option {
+	return func(c *Client) {
+		c.debug = logger
+	}
+}
+
+// WithOutbox sets a store for messages that could not be delivered so they
+// can be retried in order once the connection is healthy. Messages of type
+// "message" written while the client is disconnected are queued in the
+// outbox, as are sent messages that the server never acknowledged before
+// the connection closed. Without an outbox undeliverable messages are
+// reported as errors by Write.
+func WithOutbox(store OutboxStore) Option {
+	return func(c *Client) {
+		c.outbox = store
+	}
+}
+
+// WithAutoJoin makes the client join channels by itself as described by
+// the AutoJoin settings so the bot doesn't have to be invited everywhere.
+// Invitations need no handling since they take effect immediately.
+func WithAutoJoin(autoJoin AutoJoin) Option {
+	return func(c *Client) {
+		c.autoJoin = &autoJoin
+	}
+}
+
+// WithSendRate limits how fast the client sends to the RTM server to
+// perSecond frames on average, allowing bursts of up to burst frames.
+// Slack disconnects clients that send more than about one message per
+// second for a sustained period. Writes over the limit wait their turn,
+// failing if the connection closes first. Pings are not limited.
+func WithSendRate(perSecond float64, burst int) Option {
+	return func(c *Client) {
+		c.limiter = ratelimit.New(perSecond, burst)
+	}
+}
+
+// WithTap registers a function that receives every raw frame received
+// from and sent to the RTM server, for debugging or archiving. See TapFunc.
+func WithTap(tap TapFunc) Option {
+	return func(c *Client) {
+		c.tap = tap
+	}
+}
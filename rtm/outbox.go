@@ -0,0 +1,206 @@
+package rtm
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// OutboxStore is a first-in first-out queue of messages waiting to be
+// sent to the RTM server. A Client with an outbox stores messages that are
+// written while it is disconnected, or that were sent but never
+// acknowledged before the connection closed, and retries them in order once
+// the connection is healthy again. Implementations must be safe for
+// concurrent use. Stores that also have a Len() int method are included in
+// the send queue depth reported by Client.Status.
+type OutboxStore interface {
+	// Push appends a JSON encoded message to the back of the queue.
+	Push(msg []byte) error
+	// PushFront inserts JSON encoded messages at the front of the queue,
+	// keeping their order, so they are retried before the rest.
+	PushFront(msgs ...[]byte) error
+	// Peek returns the message at the front of the queue or nil if the
+	// queue is empty.
+	Peek() ([]byte, error)
+	// Pop removes the message at the front of the queue.
+	Pop() error
+}
+
+// MemoryOutbox is an OutboxStore that keeps messages in memory. Messages
+// survive reconnects but not restarts of the process.
+type MemoryOutbox struct {
+	mu   sync.Mutex
+	msgs [][]byte
+}
+
+// NewMemoryOutbox creates an empty in-memory outbox.
+func NewMemoryOutbox() *MemoryOutbox {
+	return &MemoryOutbox{}
+}
+
+// Push appends a message to the back of the queue.
+func (o *MemoryOutbox) Push(msg []byte) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.msgs = append(o.msgs, msg)
+	return nil
+}
+
+// PushFront inserts messages at the front of the queue in the order given.
+func (o *MemoryOutbox) PushFront(msgs ...[]byte) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.msgs = append(msgs[:len(msgs):len(msgs)], o.msgs...)
+	return nil
+}
+
+// Peek returns the message at the front of the queue or nil if the queue
+// is empty.
+func (o *MemoryOutbox) Peek() ([]byte, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if len(o.msgs) == 0 {
+		return nil, nil
+	}
+	return o.msgs[0], nil
+}
+
+// Len returns the number of messages in the queue.
+func (o *MemoryOutbox) Len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.msgs)
+}
+
+// Pop removes the message at the front of the queue.
+func (o *MemoryOutbox) Pop() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if len(o.msgs) > 0 {
+		o.msgs = o.msgs[1:]
+	}
+	return nil
+}
+
+// FileOutbox is an OutboxStore that persists messages to a file, one JSON
+// message per line, so queued messages survive restarts of the process.
+// The file is rewritten atomically every time the queue changes which
+// is fine for the handful of messages queued during an outage but not
+// intended for high volume.
+type FileOutbox struct {
+	mu   sync.Mutex
+	path string
+	msgs [][]byte
+}
+
+// NewFileOutbox opens the outbox persisted at path, loading any messages
+// left over from a previous run. The file is created when the first
+// message is pushed.
+func NewFileOutbox(path string) (*FileOutbox, error) {
+	o := &FileOutbox{path: path}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return o, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 || !json.Valid(line) {
+			continue
+		}
+		o.msgs = append(o.msgs, append([]byte(nil), line...))
+	}
+	return o, scanner.Err()
+}
+
+// Push appends a message to the back of the queue.
+func (o *FileOutbox) Push(msg []byte) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	msgs := append(o.msgs[:len(o.msgs):len(o.msgs)], msg)
+	err := o.save(msgs)
+	if err != nil {
+		return err
+	}
+	o.msgs = msgs
+	return nil
+}
+
+// PushFront inserts messages at the front of the queue in the order given.
+func (o *FileOutbox) PushFront(msgs ...[]byte) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	msgs = append(msgs[:len(msgs):len(msgs)], o.msgs...)
+	err := o.save(msgs)
+	if err != nil {
+		return err
+	}
+	o.msgs = msgs
+	return nil
+}
+
+// Peek returns the message at the front of the queue or nil if the queue
+// is empty.
+func (o *FileOutbox) Peek() ([]byte, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if len(o.msgs) == 0 {
+		return nil, nil
+	}
+	return o.msgs[0], nil
+}
+
+// Len returns the number of messages in the queue.
+func (o *FileOutbox) Len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.msgs)
+}
+
+// Pop removes the message at the front of the queue.
+func (o *FileOutbox) Pop() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if len(o.msgs) == 0 {
+		return nil
+	}
+	err := o.save(o.msgs[1:])
+	if err != nil {
+		return err
+	}
+	o.msgs = o.msgs[1:]
+	return nil
+}
+
+// save atomically replaces the outbox file with msgs.
+func (o *FileOutbox) save(msgs [][]byte) error {
+	var buf bytes.Buffer
+	for _, msg := range msgs {
+		buf.Write(msg)
+		buf.WriteByte('\n')
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(o.path), filepath.Base(o.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(buf.Bytes())
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), o.path)
+}
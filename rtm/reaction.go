@@ -0,0 +1,144 @@
+package rtm
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/gopackage/slack/api"
+	"github.com/gopackage/slack/types"
+)
+
+// Reaction is a reaction event routed by a ReactionMux together with the
+// message the reaction was added to or removed from.
+type Reaction struct {
+	*ReactionEvent
+	// Added is true for reaction_added and false for reaction_removed events.
+	Added bool
+	// Message is the message the reaction applies to. Message is nil when
+	// the reaction is on a file or the message could not be fetched.
+	Message *types.Message
+}
+
+// ReactionHandler responds to reactions routed by a ReactionMux.
+type ReactionHandler interface {
+	HandleReaction(resp ResponseWriter, r *Reaction)
+}
+
+// The ReactionHandlerFunc type is an adapter to allow the use of ordinary
+// functions as reaction handlers.
+type ReactionHandlerFunc func(ResponseWriter, *Reaction)
+
+// HandleReaction calls f(w, r).
+func (f ReactionHandlerFunc) HandleReaction(w ResponseWriter, r *Reaction) {
+	f(w, r)
+}
+
+// reactionRoute keys reaction handlers by emoji and channel.
+type reactionRoute struct {
+	emoji   string
+	channel types.ChannelID
+}
+
+// ReactionMux routes reaction_added and reaction_removed events to
+// handlers by emoji name and channel, e.g. :eyes: on the triage channel.
+// Before a handler is called the reacted-to message is fetched so the
+// handler can act on its content. Register the mux on a ServeMux for both
+// reaction event types:
+//
+//	reactions := rtm.NewReactionMux(api.NewClient(token))
+//	reactions.HandleFunc("eyes", triageChannelID, assignTicket)
+//	rtm.Handle(rtm.EventReactionAdded, reactions)
+//	rtm.Handle(rtm.EventReactionRemoved, reactions)
+type ReactionMux struct {
+	history HistoryFetcher
+
+	mu sync.RWMutex
+	m  map[reactionRoute]ReactionHandler
+}
+
+// NewReactionMux creates a ReactionMux that fetches reacted-to messages
+// with history. A nil history leaves Reaction.Message unset.
+func NewReactionMux(history HistoryFetcher) *ReactionMux {
+	return &ReactionMux{history: history, m: make(map[reactionRoute]ReactionHandler)}
+}
+
+// Handle registers the handler for reactions with the emoji (with or
+// without surrounding colons) on the channel ID. An empty channel matches
+// reactions on any channel. Skin tone variants match the base emoji.
+func (mux *ReactionMux) Handle(emoji string, channel types.ChannelID, handler ReactionHandler) {
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+
+	mux.m[reactionRoute{emoji: baseEmoji(emoji), channel: channel}] = handler
+}
+
+// HandleFunc registers the handler function for reactions with the emoji
+// on the channel ID. See Handle.
+func (mux *ReactionMux) HandleFunc(emoji string, channel types.ChannelID, handler func(ResponseWriter, *Reaction)) {
+	mux.Handle(emoji, channel, ReactionHandlerFunc(handler))
+}
+
+// Handler returns the handler registered for the reaction, preferring a
+// handler registered for the reaction's channel over one registered for
+// any channel. The handler is nil if no route matches.
+func (mux *ReactionMux) Handler(e *ReactionEvent) ReactionHandler {
+	mux.mu.RLock()
+	defer mux.mu.RUnlock()
+
+	emoji := baseEmoji(e.Reaction)
+	if h, ok := mux.m[reactionRoute{emoji: emoji, channel: e.Item.Channel}]; ok {
+		return h
+	}
+	return mux.m[reactionRoute{emoji: emoji}]
+}
+
+// HandleEvent routes reaction_added and reaction_removed events. Other
+// events are ignored. Events that fail to decode and messages that can't
+// be fetched are logged to resp's error log (see ErrorLogger).
+func (mux *ReactionMux) HandleEvent(resp ResponseWriter, event *Envelope) {
+	if event.Type != EventReactionAdded && event.Type != EventReactionRemoved {
+		return
+	}
+	var e ReactionEvent
+	err := event.Decode(&e)
+	if err != nil {
+		errorLog(resp).Println("rtm.reaction failed to decode event", err)
+		return
+	}
+	h := mux.Handler(&e)
+	if h == nil {
+		return
+	}
+	r := &Reaction{ReactionEvent: &e, Added: event.Type == EventReactionAdded}
+	if e.Item.Type == "message" && mux.history != nil {
+		r.Message, err = mux.message(e.Item.Channel, e.Item.TS)
+		if err != nil {
+			errorLog(resp).Println("rtm.reaction failed to fetch message", e.Item.Channel, e.Item.TS, err)
+		}
+	}
+	h.HandleReaction(resp, r)
+}
+
+// message fetches the message posted to channel at ts.
+func (mux *ReactionMux) message(channel types.ChannelID, ts types.Timestamp) (*types.Message, error) {
+	r, err := mux.history.ConversationHistory(channel, api.HistoryParams{Latest: ts, Inclusive: true, Limit: 1})
+	if err != nil {
+		return nil, err
+	}
+	if len(r.Messages) == 0 || r.Messages[0].TS != ts {
+		// Thread replies aren't part of the channel history.
+		return nil, nil
+	}
+	msg := r.Messages[0]
+	msg.Channel = channel
+	return &msg, nil
+}
+
+// baseEmoji strips colons and skin tone modifiers from an emoji name.
+func baseEmoji(emoji string) string {
+	emoji = strings.Trim(emoji, ":")
+	if i := strings.Index(emoji, "::"); i >= 0 {
+		emoji = emoji[:i]
+	}
+	return emoji
+}
@@ -2,15 +2,23 @@
 package rtm
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
-	"os"
+	"runtime"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/gopackage/slack/api"
+	"github.com/gopackage/slack/internal/ratelimit"
+	"github.com/gopackage/slack/types"
 	"golang.org/x/net/websocket"
 )
 
@@ -28,10 +36,10 @@ func NewServeMux() *ServeMux {
 // ordinary functions as event handlers.  If f is a function
 // with the appropriate signature, HandlerFunc(f) is a
 // Handler object that calls f.
-type HandlerFunc func(ResponseWriter, interface{})
+type HandlerFunc func(ResponseWriter, *Envelope)
 
 // HandleEvent calls f(w, r).
-func (f HandlerFunc) HandleEvent(w ResponseWriter, e interface{}) {
+func (f HandlerFunc) HandleEvent(w ResponseWriter, e *Envelope) {
 	f(w, e)
 }
 
@@ -42,30 +50,62 @@ type eventHandler struct {
 	pattern string
 }
 
-// ServeMux is an RTM event multixplexer. It matches incoming RTM events
-// by type and calls the handler that most closely matches the pattern.
+// ServeMux is an event multixplexer. It matches incoming events by type and
+// calls the handler that most closely matches the pattern. Events are routed
+// the same way whether they arrive over RTM, Socket Mode or the Events API.
 // Pattern matching resolves to the "best" match (most precise).
-// Handlers that register identical patterns will be dispatched to by random.
+// Handlers that register identical patterns are all called, in the order
+// they were registered, so independent plugins can handle the same events.
 type ServeMux struct {
 	mu sync.RWMutex
 	m  map[string]eventHandler
 }
 
 // Handle adds a Handler that will be dispatched when any event that matches
-// the provided pattern is received.
+// the provided pattern is received. Handlers already registered for the
+// pattern are kept and called first.
 func (mux *ServeMux) Handle(pattern string, handler Handler) {
 	mux.mu.Lock()
 	defer mux.mu.Unlock()
 
-	e := eventHandler{handler: handler, pattern: pattern}
-	mux.m[pattern] = e
+	if e, ok := mux.m[pattern]; ok {
+		handlers, _ := e.handler.(multiHandler)
+		if handlers == nil {
+			handlers = multiHandler{e.handler}
+		}
+		handler = append(handlers[:len(handlers):len(handlers)], handler)
+	}
+	mux.m[pattern] = eventHandler{handler: handler, pattern: pattern}
+}
+
+// multiHandler calls several handlers registered for the same pattern.
+type multiHandler []Handler
+
+// HandleEvent passes the event to every handler in turn. A panic in one
+// handler doesn't stop the others being called; the first panic is
+// re-raised once they have all run so it is reported as usual.
+func (m multiHandler) HandleEvent(resp ResponseWriter, event *Envelope) {
+	var panicked interface{}
+	for _, h := range m {
+		func() {
+			defer func() {
+				if err := recover(); err != nil && panicked == nil {
+					panicked = err
+				}
+			}()
+			h.HandleEvent(resp, event)
+		}()
+	}
+	if panicked != nil {
+		panic(panicked)
+	}
 }
 
 // HandleFunc adds a handler that will be dispatched when an event that
 // matches the provided pattern is received. The redundant functionality
 // matches net/http and makes up for the difference in Go between anonmyous
 // functions and interfaces.
-func (mux *ServeMux) HandleFunc(pattern string, handler func(resp ResponseWriter, event interface{})) {
+func (mux *ServeMux) HandleFunc(pattern string, handler func(resp ResponseWriter, event *Envelope)) {
 	mux.Handle(pattern, HandlerFunc(handler))
 }
 
@@ -73,23 +113,22 @@ func (mux *ServeMux) HandleFunc(pattern string, handler func(resp ResponseWriter
 // handler return can be nil indicating no handlers are registered for
 // the provided pattern. If the handler is non-nil the matching pattern
 // is also returned (for debugging/testing).
-func (mux *ServeMux) Handler(event interface{}) (h Handler, pattern string) {
+func (mux *ServeMux) Handler(event *Envelope) (h Handler, pattern string) {
 	mux.mu.Lock()
 	defer mux.mu.Unlock()
 
 	// Currently we only support exact pattern matches. Would be nice to
 	// at least add wild cards at some point or regular expressions.
-	eType := event.(map[string]interface{})["type"].(string)
-	e, ok := mux.m[eType]
+	e, ok := mux.m[event.Type]
 	if ok {
 		return e.handler, e.pattern
 	}
 	return nil, ""
 }
 
-// HandleEvent handles any incoming event from an RTM stream. Responses
+// HandleEvent handles any incoming event from any transport. Responses
 // may be written to the ResponseWritter (but is not required).
-func (mux *ServeMux) HandleEvent(resp ResponseWriter, event interface{}) {
+func (mux *ServeMux) HandleEvent(resp ResponseWriter, event *Envelope) {
 	// Can do some pre-processing, logging, stats, etc here...
 	h, _ := mux.Handler(event)
 	if h != nil {
@@ -97,8 +136,10 @@ func (mux *ServeMux) HandleEvent(resp ResponseWriter, event interface{}) {
 	}
 }
 
-// ResponseWriter interface provides the methods for Handlers to write
-// to an active rtm connection.
+// ResponseWriter interface provides the methods for Handlers to reply to
+// events. The Client writes to its active rtm connection; transports
+// without one write through the Web API (see APIWriter) and return
+// ErrNotSupported for writes they can't make.
 type ResponseWriter interface {
 	// Write sends the data to the connection as part of an RTM reply.
 	// The event object must be JSON serializable.
@@ -106,7 +147,27 @@ type ResponseWriter interface {
 	Write(event map[string]interface{}) (int, error)
 	// WriteMsg sends a simple RTM message. This is a simple convenience
 	// for sending message objects to the RTM server.
-	WriteMsg(channel, text string) (int, error)
+	WriteMsg(channel types.ChannelID, text string) (int, error)
+	// WriteTyping sends a typing indicator to the channel. Slack shows the
+	// indicator for a few seconds or until a message is sent, so handlers
+	// doing slow work should send it again periodically.
+	WriteTyping(channel types.ChannelID) (int, error)
+}
+
+// ErrorLogger is implemented by ResponseWriters whose transport has a
+// logger for handler errors, such as events that fail to decode. Handlers
+// in this package log to it, or to the log package for ResponseWriters
+// that don't implement it.
+type ErrorLogger interface {
+	ErrorLog() *log.Logger
+}
+
+// errorLog returns the logger handlers writing to resp report errors to.
+func errorLog(resp ResponseWriter) *log.Logger {
+	if l, ok := resp.(ErrorLogger); ok && l.ErrorLog() != nil {
+		return l.ErrorLog()
+	}
+	return log.Default()
 }
 
 // Handler interface should be implemented by any object that wants to
@@ -121,16 +182,74 @@ type ResponseWriter interface {
 // It recovers the panic, logs a stack trace to the server error log, and
 // continues received events.
 type Handler interface {
-	HandleEvent(resp ResponseWriter, event interface{})
+	HandleEvent(resp ResponseWriter, event *Envelope)
 }
 
+// ErrClientClosed is returned by the Client's DialAndListen method after a
+// call to Shutdown.
+var ErrClientClosed = errors.New("rtm: Client closed")
+
+// ErrNotConnected is returned when writing to a Client that has no open
+// RTM connection.
+var ErrNotConnected = errors.New("rtm: not connected")
+
+// ErrPongTimeout is returned by the Client's DialAndListen method when the
+// server did not answer a ping within the pong timeout.
+var ErrPongTimeout = errors.New("rtm: pong timeout")
+
+// shutdownPollInterval is how often Shutdown checks whether the Client
+// has finished its in-flight work.
+const shutdownPollInterval = 50 * time.Millisecond
+
 // Client is a Slack Real-Time Messaging (RTM) client.
 //
-// Clients contain state information so they should be created instead of
-// reused.
+// Clients must be created with NewClient; the zero value has no logger or
+// HTTP client and is not usable. A Client may be run again after its
+// connection is lost to reconnect, keeping its outbox, IM channels and
+// Status counters, but not once Shutdown has been called.
 type Client struct {
-	ws     *websocket.Conn
-	sendID int64
+	token          string
+	origin         string
+	pingInterval   time.Duration
+	pongTimeout    time.Duration
+	writeTimeout   time.Duration
+	readBufferSize int
+	timeout        time.Duration
+	httpClient     *http.Client
+	api            *api.Client
+	logger         *log.Logger
+	debug          *log.Logger
+	outbox         OutboxStore
+	tap            TapFunc
+	autoJoin       *AutoJoin
+	limiter        *ratelimit.Limiter
+
+	sendID int64 // accessed atomically
+
+	inShutdown int32 // accessed atomically (non-zero means we're in Shutdown)
+	inFlight   int64 // accessed atomically, handlers currently running
+	writing    int64 // accessed atomically, writes queued or in progress
+	flushing   int32 // accessed atomically (non-zero while the outbox is flushed)
+
+	lastEvent    int64 // accessed atomically, unix nanoseconds of the last frame read
+	handlerStart int64 // accessed atomically, unix nanoseconds the running handler was called, zero if none
+
+	mu      sync.Mutex // guards ws, ready, pending and the status fields below
+	ws      *websocket.Conn
+	ready   bool             // true once the server has said hello
+	pending map[int64][]byte // sent messages awaiting a reply, with the outbox copy if any
+
+	connections int       // number of connections opened
+	connectedAt time.Time // when the current connection was opened
+	pingID      int64     // id of the last ping sent
+	pingSent    time.Time // when the unanswered ping was sent, zero once answered
+	pingLatency time.Duration
+
+	writes chan *writeRequest // frames for the writer goroutine, nil when not connected
+	done   chan struct{}      // closed when the connection closes
+
+	imMu sync.Mutex                       // guards ims
+	ims  map[types.UserID]types.ChannelID // IM channel IDs keyed by user ID
 }
 
 // DialAndListen opens a connection to the Slack RTM server and begins
@@ -140,90 +259,447 @@ type Client struct {
 // events, a handler should be registered for the "hello" event. When the
 // hello event is received the RTM connection has been received and the
 // ResponseWriter can be saved and used to send messages.
-func (c *Client) DialAndListen(token string, handler Handler) (err error) {
+//
+// DialAndListen is Run with a background context. It always returns a
+// non-nil error. After Shutdown the returned error is ErrClientClosed.
+func (c *Client) DialAndListen(handler Handler) error {
+	return c.Run(context.Background(), handler)
+}
+
+// dial calls rtm.start and opens the websocket it returns.
+func (c *Client) dial() (*websocket.Conn, error) {
 	// Hit the rtm.start endpoint and get the websocket
-	log.Println("rtm.start")
-	resp, err := http.Get("https://slack.com/api/rtm.start?token=" + token)
+	c.debug.Println("rtm.start")
+	resp, err := c.httpClient.Get("https://slack.com/api/rtm.start?token=" + c.token)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	log.Println("rtm.started")
+	c.debug.Println("rtm.started")
 	defer resp.Body.Close()
 	body, err := ioutil.ReadAll(resp.Body)
-	log.Println("rtm.start body", len(body))
+	c.debug.Println("rtm.start body", len(body))
 
 	var r StartResponse
 	err = json.Unmarshal(body, &r)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	log.Println("rtm.start body parsed", r.Ok, r.Error, r.URL)
+	c.debug.Println("rtm.start body parsed", r.Ok, r.Error, r.URL)
 
 	if !r.Ok {
-		return fmt.Errorf("RTM API was not OK to start stream: %s", r.Error)
+		return nil, fmt.Errorf("RTM API was not OK to start stream: %s", r.Error)
 	}
 
-	origin := os.Getenv("BITBOT_ORIGIN")
-	log.Println("rtm.start origin", origin)
-	c.ws, err = websocket.Dial(r.URL, "", origin)
+	c.debug.Println("rtm.start origin", c.origin)
+	config, err := websocket.NewConfig(r.URL, c.origin)
+	if err != nil {
+		return nil, err
+	}
+	config.Dialer = &net.Dialer{Timeout: c.timeout}
+	ws, err := websocket.DialConfig(config)
 	if err != nil {
-		log.Println("rtm.start encountered websocket.Dial", err)
-		return err
+		c.logger.Println("rtm.start encountered websocket.Dial", err)
+		return nil, err
 	}
-	log.Println("rtm.start ws dialed")
+	c.debug.Println("rtm.start ws dialed")
+	ws.MaxPayloadBytes = c.readBufferSize
+	return ws, nil
+}
 
-	defer c.ws.Close()
+// dispatch passes the event to the handler. The event was counted as
+// in-flight when it was queued and stops being in-flight when the handler
+// returns. Handler panics are recovered and logged.
+func (c *Client) dispatch(handler Handler, event *Envelope) {
+	defer atomic.AddInt64(&c.inFlight, -1)
+	atomic.StoreInt64(&c.handlerStart, time.Now().UnixNano())
+	defer atomic.StoreInt64(&c.handlerStart, 0)
+	defer func() {
+		if err := recover(); err != nil {
+			buf := make([]byte, 64<<10)
+			buf = buf[:runtime.Stack(buf, false)]
+			c.logger.Printf("rtm: panic handling event %s: %v\n%s", event.Raw, err, buf)
+		}
+	}()
+	handler.HandleEvent(c, event)
+}
 
-	// Listen to the connection sending events to the event handler.
-	msg := make([]byte, 4096)
-	watchdog := time.AfterFunc(25*time.Second, func() {
-		c.Write(map[string]interface{}{"type": "ping"})
-	})
+// ack clears a pending message when the server replies to it.
+func (c *Client) ack(event *Envelope) {
+	if event.replyTo == nil {
+		return
+	}
+	id := *event.replyTo
+	if event.ok != nil && !*event.ok {
+		c.logger.Println("rtm: server rejected message", id, event.err)
+	}
+	c.mu.Lock()
+	delete(c.pending, id)
+	if id == c.pingID && !c.pingSent.IsZero() {
+		c.pingLatency = time.Since(c.pingSent)
+		c.pingSent = time.Time{}
+	}
+	c.mu.Unlock()
+}
 
-	log.Println("rtm.start ready to read event")
+// Shutdown gracefully shuts down the client without interrupting any
+// active handlers. Shutdown works by first no longer handing incoming
+// events to the handler, then waiting for in-flight handlers to return,
+// queued writes to be sent and sent messages to be acknowledged by the
+// server, and then closing the connection. Once Shutdown has been called
+// DialAndListen returns ErrClientClosed.
+//
+// If the provided context expires before the shutdown is complete, the
+// connection is closed anyway and Shutdown returns the context's error.
+func (c *Client) Shutdown(ctx context.Context) error {
+	atomic.StoreInt32(&c.inShutdown, 1)
+
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
 	for {
-		var read int
-		for read, err = c.ws.Read(msg); read == 4096 || err != nil; read, err = c.ws.Read(msg) {
-			// Buffer not big enough - we read until drained
-			if read == 0 {
-				// This can loop infinitely fast with read == 0 so we will
-				// sleep so we don't use up all the available CPU.
-				log.Println("rtm.start ######### ws timeout")
-				time.Sleep(1 * time.Second)
-			} else {
-				log.Println("rtm.start reading event", read)
+		if c.idle() {
+			c.closeConn()
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			c.closeConn()
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// shuttingDown returns true once Shutdown has been called.
+func (c *Client) shuttingDown() bool {
+	return atomic.LoadInt32(&c.inShutdown) != 0
+}
+
+// idle returns true if there are no running handlers, queued writes or
+// unacknowledged messages.
+func (c *Client) idle() bool {
+	if atomic.LoadInt64(&c.inFlight) != 0 || atomic.LoadInt64(&c.writing) != 0 {
+		return false
+	}
+	if atomic.LoadInt32(&c.flushing) != 0 || (c.connected() && c.outboxWaiting()) {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ws == nil || len(c.pending) == 0
+}
+
+// connected returns true if the connection is open and the server has
+// said hello.
+func (c *Client) connected() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ws != nil && c.ready
+}
+
+// closeConn closes the active connection, if any. Messages that were never
+// acknowledged are moved to the outbox so they are retried on the next
+// connection.
+func (c *Client) closeConn() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ws == nil {
+		return
+	}
+	c.ws.Close()
+	c.ws = nil
+	c.ready = false
+	close(c.done)
+	c.done = nil
+	c.writes = nil
+
+	var ids []int64
+	for id, data := range c.pending {
+		if data != nil {
+			ids = append(ids, id)
+		}
+	}
+	// The unacknowledged messages were sent before anything still in the
+	// outbox, so they go back in front of it in the order they were sent.
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	msgs := make([][]byte, len(ids))
+	for i, id := range ids {
+		msgs[i] = c.pending[id]
+	}
+	if len(msgs) > 0 {
+		err := c.outbox.PushFront(msgs...)
+		if err != nil {
+			c.logger.Println("rtm.outbox failed to store unacknowledged messages", ids, err)
+		}
+	}
+	c.pending = nil
+}
+
+// outboxWaiting returns true if the outbox has messages waiting to be sent.
+func (c *Client) outboxWaiting() bool {
+	if c.outbox == nil {
+		return false
+	}
+	data, err := c.outbox.Peek()
+	return err != nil || data != nil
+}
+
+// flushOutbox starts sending the messages waiting in the outbox unless a
+// flush is already running or the client isn't connected.
+func (c *Client) flushOutbox() {
+	if c.outbox == nil || !c.connected() {
+		return
+	}
+	if !atomic.CompareAndSwapInt32(&c.flushing, 0, 1) {
+		return
+	}
+	go func() {
+		for {
+			c.drainOutbox()
+			atomic.StoreInt32(&c.flushing, 0)
+			// A message may have been queued after the drain found the
+			// outbox empty but before the flag was cleared.
+			if !c.connected() || !c.outboxWaiting() || !atomic.CompareAndSwapInt32(&c.flushing, 0, 1) {
+				return
+			}
+		}
+	}()
+}
+
+// drainOutbox sends messages from the front of the outbox until it is
+// empty or a send fails. Each message is removed from the outbox by send
+// as it becomes pending, so it is always in exactly one of the two.
+func (c *Client) drainOutbox() {
+	for c.connected() {
+		data, err := c.outbox.Peek()
+		if err != nil {
+			c.logger.Println("rtm.outbox failed to read message", err)
+			return
+		}
+		if data == nil {
+			return
+		}
+		var msg map[string]interface{}
+		err = json.Unmarshal(data, &msg)
+		if err != nil {
+			c.logger.Println("rtm.outbox dropping invalid message", string(data), err)
+			err = c.outbox.Pop()
+			if err != nil {
+				c.logger.Println("rtm.outbox failed to remove invalid message", err)
+				return
 			}
+			continue
 		}
-		watchdog.Reset(25 * time.Second)
-		var event interface{}
-		err = json.Unmarshal(msg[0:read], &event)
+		_, err = c.send(msg, data, true)
 		if err != nil {
-			// packet no good, we ignore it for now
-			log.Println("rtm.start ###### error parsing event", string(msg[0:read]), err)
-		} else {
-			log.Println("rtm.start handling event", string(msg[0:read]))
-			handler.HandleEvent(c, event)
+			c.logger.Println("rtm.outbox failed to send message", err)
+			return
 		}
 	}
 }
 
 // Write sends the provided msg to the RTM server. All msgs must contain
-// a "type" field. The "id" field will be automatically configured by the client.
+// a "type" field. The "id" field of the frame sent is set by the client;
+// msg itself is not modified.
+//
+// If the client has an outbox (see WithOutbox), messages of type "message"
+// that can't be sent right away are stored in the outbox and Write reports
+// success.
 func (c *Client) Write(msg map[string]interface{}) (int, error) {
-	msg["id"] = c.sendID
-	c.sendID++
-	log.Printf("rtm.start write %v", msg)
+	atomic.AddInt64(&c.writing, 1)
+	defer atomic.AddInt64(&c.writing, -1)
+
+	// The id is set per send, so work on a copy to leave the caller's
+	// message alone.
+	m := make(map[string]interface{}, len(msg)+1)
+	for k, v := range msg {
+		m[k] = v
+	}
+	msg = m
+
+	var stored []byte
+	if c.outbox != nil && msg["type"] == EventMessage {
+		delete(msg, "id")
+		data, err := json.Marshal(msg)
+		if err != nil {
+			return -1, err
+		}
+		stored = data
+		// Messages already waiting in the outbox must go first.
+		if !c.connected() || c.outboxWaiting() {
+			err = c.outbox.Push(stored)
+			if err != nil {
+				return -1, err
+			}
+			c.flushOutbox()
+			return len(stored), nil
+		}
+	}
+	n, err := c.send(msg, stored, false)
+	if err != nil && stored != nil {
+		if c.outbox.Push(stored) != nil {
+			return n, err
+		}
+		return len(stored), nil
+	}
+	return n, err
+}
+
+// send queues msg for the writer and, if the server replies to it, records
+// it as awaiting a reply.
+// The stored copy is moved to the outbox if no reply is received before
+// the connection closes. If queued is true the stored copy is the message
+// at the front of the outbox and is popped as it is recorded as pending,
+// or put back if it couldn't be written.
+func (c *Client) send(msg map[string]interface{}, stored []byte, queued bool) (int, error) {
+	id := atomic.AddInt64(&c.sendID, 1) - 1
+	msg["id"] = id
+	c.debug.Printf("rtm.start write %v", msg)
 	data, err := json.Marshal(msg)
 	if err != nil {
 		return -1, err
 	}
-	return c.ws.Write(data)
+
+	// Pings keep the connection alive so they don't wait their turn.
+	if c.limiter != nil && msg["type"] != EventPing {
+		err = c.waitLimiter()
+		if err != nil {
+			return -1, err
+		}
+	}
+
+	// closeConn also holds c.mu while it moves pending messages to the
+	// outbox, so a queued message can't be lost or stored twice.
+	c.mu.Lock()
+	writes, done := c.writes, c.done
+	if writes != nil && queued {
+		err = c.outbox.Pop()
+	}
+	if writes != nil && err == nil && expectsReply(msg) {
+		c.pending[id] = stored
+	}
+	c.mu.Unlock()
+	if writes == nil {
+		return -1, c.notConnected()
+	}
+	if err != nil {
+		return -1, err
+	}
+	err = c.queueWrite(writes, done, data)
+	if err != nil {
+		c.mu.Lock()
+		_, waiting := c.pending[id]
+		delete(c.pending, id)
+		if waiting && queued {
+			if pushErr := c.outbox.PushFront(stored); pushErr != nil {
+				c.logger.Println("rtm.outbox failed to put back unsent message", id, pushErr)
+			}
+		}
+		c.mu.Unlock()
+		if !waiting && stored != nil {
+			// The connection closed while the message was queued and
+			// closeConn has already moved it to the outbox.
+			return len(stored), nil
+		}
+		return -1, err
+	}
+	c.tapFrame(Outbound, msg, data)
+	return len(data), nil
+}
+
+// expectsReply returns true if the server replies to the frame. Other
+// frames, such as typing indicators, are fire and forget and aren't
+// tracked as pending.
+func expectsReply(msg map[string]interface{}) bool {
+	return msg["type"] == EventMessage || msg["type"] == EventPing
+}
+
+// waitLimiter waits for the send rate limiter to allow a frame, giving up
+// if the connection closes first.
+func (c *Client) waitLimiter() error {
+	c.mu.Lock()
+	done := c.done
+	c.mu.Unlock()
+	if done == nil {
+		return c.notConnected()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	if c.limiter.Wait(ctx) != nil {
+		return c.notConnected()
+	}
+	return nil
+}
+
+// notConnected returns the error for a write made without a connection.
+func (c *Client) notConnected() error {
+	if c.shuttingDown() {
+		return ErrClientClosed
+	}
+	return ErrNotConnected
 }
 
 // WriteMsg is a simple convenience for sending RTM simple text messages.
 // The "id" field will be automatically configured by the client.
-func (c *Client) WriteMsg(channel, text string) (int, error) {
-	return c.Write(map[string]interface{}{"type": "message", "channel": channel, "text": text})
+func (c *Client) WriteMsg(channel types.ChannelID, text string) (int, error) {
+	return c.Write(map[string]interface{}{"type": EventMessage, "channel": channel, "text": text})
+}
+
+// WriteTyping sends an RTM typing indicator to the channel.
+// The "id" field will be automatically configured by the client.
+func (c *Client) WriteTyping(channel types.ChannelID) (int, error) {
+	return c.Write(map[string]interface{}{"type": EventTyping, "channel": channel})
+}
+
+// ErrorLog returns the logger set with WithLogger, which handlers report
+// errors to. It implements ErrorLogger.
+func (c *Client) ErrorLog() *log.Logger {
+	return c.logger
+}
+
+// API returns the Web API client used by helpers such as DM.
+func (c *Client) API() *api.Client {
+	return c.api
+}
+
+// DM sends a direct message to the user. The IM channel with the user is
+// opened with the Web API the first time and reused for later messages.
+func (c *Client) DM(user types.UserID, text string) (int, error) {
+	channel, err := c.imChannel(user)
+	if err != nil {
+		return -1, err
+	}
+	return c.WriteMsg(channel, text)
+}
+
+// imChannel returns the ID of the IM channel with the user, opening it if
+// it hasn't been opened yet.
+func (c *Client) imChannel(user types.UserID) (types.ChannelID, error) {
+	c.imMu.Lock()
+	channel, ok := c.ims[user]
+	c.imMu.Unlock()
+	if ok {
+		return channel, nil
+	}
+	// Don't hold the lock during the call so DMs to other users aren't
+	// held up. Opening the same IM twice returns the same channel.
+	r, err := c.api.OpenConversation(user)
+	if err != nil {
+		return "", err
+	}
+	c.imMu.Lock()
+	defer c.imMu.Unlock()
+	if c.ims == nil {
+		c.ims = make(map[types.UserID]types.ChannelID)
+	}
+	c.ims[user] = r.Channel.ID
+	return r.Channel.ID, nil
 }
 
 // Handle adds a handler for an event on the DefaultServeMux.
@@ -234,7 +710,7 @@ func Handle(pattern string, handler Handler) {
 
 // HandleFunc adds a handler functino for an event on the DefaultServeMux.
 // See ServeMux documentation for usage.
-func HandleFunc(pattern string, handler func(resp ResponseWriter, event interface{})) {
+func HandleFunc(pattern string, handler func(resp ResponseWriter, event *Envelope)) {
 	DefaultServeMux.HandleFunc(pattern, handler)
 }
 
@@ -246,8 +722,7 @@ func HandleFunc(pattern string, handler func(resp ResponseWriter, event interfac
 // hello event is received the RTM connection has been received and the
 // ResponseWriter can be saved and used to send messages.
 func DialAndListen(token string) (err error) {
-	client := Client{}
-	return client.DialAndListen(token, DefaultServeMux)
+	return NewClient(token).DialAndListen(DefaultServeMux)
 }
 
 // StartResponse is received from the Slack rtm.start API.
@@ -259,10 +734,12 @@ type StartResponse struct {
 	// URL is the web socket to connect to (must be used within 30 sec)
 	// e.g. "wss:\/\/ms9.slack-msgs.com\/websocket\/7I5yBpcvk"
 	URL string `json:"url"`
+	// Self describes the connected user
+	Self Self `json:"self"`
+	// Team describes the team the user is connected to
+	Team Team `json:"team"`
 
 	// TODO these should be a "database"
-	//Self Self `json:"self"`
-	//Team Team `json:"team"`
 	//Users []string `json:"users"`
 	//Channels []string `json:"channels"`
 	//Groups   []string `json:"groups"`
@@ -273,7 +750,7 @@ type StartResponse struct {
 // Self describes the user's account
 type Self struct {
 	// ID uuid for the user e.g. "U023BECGF",
-	ID string `json:"id"`
+	ID types.UserID `json:"id"`
 	// Name of the user e.g. "bobby"
 	Name string `json:"name"`
 	// Preferences for the user
@@ -290,7 +767,7 @@ type Preferences map[string]interface{}
 // Team contains information on the teams the user belongs to.
 type Team struct {
 	// ID is the uuid for the team e.g. T024BE7LD
-	ID string `json:"id"`
+	ID types.TeamID `json:"id"`
 	// Name is the name of the slack team
 	Name string `json:"name"`
 	// EmailDomain is the slack default email domain for team members (can be empty)
@@ -0,0 +1,393 @@
+package rtm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gopackage/slack/api"
+	"github.com/gopackage/slack/types"
+	"golang.org/x/net/websocket"
+)
+
+// fakeSlack serves rtm.start and the RTM websocket it points to. Each
+// accepted connection is handed to the test, which says hello, reads and
+// answers frames and closes it as the test needs.
+type fakeSlack struct {
+	srv   *httptest.Server
+	conns chan *fakeConn
+	// onStart, if set, is called when rtm.start is called.
+	onStart func()
+}
+
+// fakeConn is a connection accepted by fakeSlack.
+type fakeConn struct {
+	t    *testing.T
+	ws   *websocket.Conn
+	done chan struct{}
+}
+
+func newFakeSlack(t *testing.T) *fakeSlack {
+	s := &fakeSlack{conns: make(chan *fakeConn, 1)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/rtm.start", func(w http.ResponseWriter, r *http.Request) {
+		if s.onStart != nil {
+			s.onStart()
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"ok":  true,
+			"url": "ws://" + r.Host + "/ws",
+		})
+	})
+	mux.Handle("/ws", websocket.Handler(func(ws *websocket.Conn) {
+		c := &fakeConn{t: t, ws: ws, done: make(chan struct{})}
+		s.conns <- c
+		<-c.done
+	}))
+	s.srv = httptest.NewServer(mux)
+	t.Cleanup(s.srv.Close)
+	return s
+}
+
+// client creates a client connecting to the fake server.
+func (s *fakeSlack) client(options ...Option) *Client {
+	u, _ := url.Parse(s.srv.URL)
+	options = append([]Option{
+		WithHTTPClient(&http.Client{Transport: rewriteHost{u.Host}}),
+		WithPingInterval(0),
+		WithDebugLogger(discardLogger()),
+	}, options...)
+	return NewClient("xoxb-test", options...)
+}
+
+// accept waits for the client to connect and says hello.
+func (s *fakeSlack) accept(t *testing.T) *fakeConn {
+	select {
+	case c := <-s.conns:
+		c.send(map[string]interface{}{"type": EventHello})
+		return c
+	case <-time.After(5 * time.Second):
+		t.Fatal("client didn't connect")
+		return nil
+	}
+}
+
+func (c *fakeConn) send(v interface{}) {
+	if err := websocket.JSON.Send(c.ws, v); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// read reads the next frame written by the client.
+func (c *fakeConn) read() map[string]interface{} {
+	c.ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var frame map[string]interface{}
+	if err := websocket.JSON.Receive(c.ws, &frame); err != nil {
+		c.t.Fatal(err)
+	}
+	return frame
+}
+
+// reply acknowledges a message frame as Slack does.
+func (c *fakeConn) reply(frame map[string]interface{}) {
+	c.send(map[string]interface{}{"ok": true, "reply_to": frame["id"], "text": frame["text"]})
+}
+
+func (c *fakeConn) close() {
+	c.ws.Close()
+	close(c.done)
+}
+
+// rewriteHost sends every request to host over plain HTTP.
+type rewriteHost struct{ host string }
+
+func (r rewriteHost) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = "http"
+	req.URL.Host = r.host
+	req.Host = r.host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// run runs the client until the returned function is called, which waits
+// for Run to return and reports its error.
+func run(t *testing.T, c *Client) func() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		errc <- c.Run(ctx, HandlerFunc(func(ResponseWriter, *Envelope) {}))
+	}()
+	return func() error {
+		select {
+		case err := <-errc:
+			cancel()
+			return err
+		case <-time.After(5 * time.Second):
+			cancel()
+			t.Fatal("Run didn't return")
+			return nil
+		}
+	}
+}
+
+// waitFor polls until cond is true.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestOutboxRetriesUnacknowledgedMessagesInOrder(t *testing.T) {
+	s := newFakeSlack(t)
+	outbox := NewMemoryOutbox()
+	c := s.client(WithOutbox(outbox))
+
+	// The first connection reads two messages but drops before replying.
+	wait := run(t, c)
+	conn := s.accept(t)
+	waitFor(t, "connection", func() bool { return c.Status().Connected })
+	for _, text := range []string{"one", "two"} {
+		if _, err := c.WriteMsg("C1", text); err != nil {
+			t.Fatal(err)
+		}
+		conn.read()
+	}
+	conn.close()
+	if wait() == nil {
+		t.Fatal("Run returned nil")
+	}
+	// Written while disconnected, so queued behind the unacknowledged ones.
+	if _, err := c.WriteMsg("C1", "three"); err != nil {
+		t.Fatal(err)
+	}
+	if n := outbox.Len(); n != 3 {
+		t.Fatalf("outbox has %d messages, want 3", n)
+	}
+
+	// The second connection reads the retried messages but drops again
+	// before replying to any of them.
+	wait = run(t, c)
+	conn = s.accept(t)
+	for _, want := range []string{"one", "two", "three"} {
+		if got := conn.read()["text"]; got != want {
+			t.Fatalf("second connection got %v, want %s", got, want)
+		}
+	}
+	conn.close()
+	wait()
+	if n := outbox.Len(); n != 3 {
+		t.Fatalf("outbox has %d messages, want 3", n)
+	}
+
+	// The third connection gets every message exactly once, in order.
+	wait = run(t, c)
+	conn = s.accept(t)
+	for _, want := range []string{"one", "two", "three"} {
+		frame := conn.read()
+		if got := frame["text"]; got != want {
+			t.Fatalf("third connection got %v, want %s", got, want)
+		}
+		conn.reply(frame)
+	}
+	waitFor(t, "replies", func() bool { return c.Status().Pending == 0 })
+	if n := outbox.Len(); n != 0 {
+		t.Errorf("outbox has %d messages, want 0", n)
+	}
+	c.Shutdown(context.Background())
+	conn.close()
+	if err := wait(); err != ErrClientClosed {
+		t.Errorf("Run = %v, want %v", err, ErrClientClosed)
+	}
+}
+
+func TestShutdownDuringDial(t *testing.T) {
+	s := newFakeSlack(t)
+	started, proceed := make(chan struct{}), make(chan struct{})
+	s.onStart = func() {
+		close(started)
+		<-proceed
+	}
+	c := s.client()
+	wait := run(t, c)
+	<-started
+	if err := c.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	close(proceed)
+	if err := wait(); err != ErrClientClosed {
+		t.Errorf("Run = %v, want %v", err, ErrClientClosed)
+	}
+}
+
+func TestWriteDoesNotModifyMessage(t *testing.T) {
+	s := newFakeSlack(t)
+	c := s.client(WithOutbox(NewMemoryOutbox()))
+	msg := map[string]interface{}{"type": EventMessage, "channel": "C1", "text": "hi", "id": 7}
+	if _, err := c.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+	if len(msg) != 4 || msg["id"] != 7 {
+		t.Errorf("Write changed the message to %v", msg)
+	}
+}
+
+func TestTypingIsNotPending(t *testing.T) {
+	s := newFakeSlack(t)
+	c := s.client()
+	wait := run(t, c)
+	conn := s.accept(t)
+	waitFor(t, "connection", func() bool { return c.Status().Connected })
+	if _, err := c.WriteTyping("C1"); err != nil {
+		t.Fatal(err)
+	}
+	if got := conn.read()["type"]; got != EventTyping {
+		t.Fatalf("got %v frame, want %s", got, EventTyping)
+	}
+	if n := c.Status().Pending; n != 0 {
+		t.Errorf("%d frames pending, want 0", n)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := c.Shutdown(ctx); err != nil {
+		t.Errorf("Shutdown = %v", err)
+	}
+	conn.close()
+	wait()
+}
+
+// holdOutbox is a MemoryOutbox whose Pop of a message that has already
+// been written waits until release is closed, so the connection can be
+// dropped at the worst moment.
+type holdOutbox struct {
+	*MemoryOutbox
+	written func(data []byte) bool
+	release chan struct{}
+}
+
+func (o *holdOutbox) Pop() error {
+	if data, _ := o.Peek(); data != nil && o.written(data) {
+		<-o.release
+	}
+	return o.MemoryOutbox.Pop()
+}
+
+func TestOutboxDisconnectWhileDraining(t *testing.T) {
+	s := newFakeSlack(t)
+	var sentTwo int32
+	outbox := &holdOutbox{
+		MemoryOutbox: NewMemoryOutbox(),
+		written: func(data []byte) bool {
+			return strings.Contains(string(data), `"two"`) && atomic.LoadInt32(&sentTwo) != 0
+		},
+		release: make(chan struct{}),
+	}
+	c := s.client(WithOutbox(outbox), WithTap(func(f Frame) {
+		if f.Direction == Outbound && f.Event["text"] == "two" {
+			atomic.StoreInt32(&sentTwo, 1)
+		}
+	}))
+	for _, text := range []string{"one", "two"} {
+		if _, err := c.WriteMsg("C1", text); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The outbox is drained on hello; the connection drops once both
+	// messages have been written but before either is acknowledged.
+	wait := run(t, c)
+	conn := s.accept(t)
+	conn.read()
+	conn.read()
+	conn.close()
+	wait()
+	close(outbox.release)
+	waitFor(t, "drain to stop", func() bool { return atomic.LoadInt32(&c.flushing) == 0 })
+
+	wait = run(t, c)
+	conn = s.accept(t)
+	for _, want := range []string{"one", "two"} {
+		frame := conn.read()
+		if got := frame["text"]; got != want {
+			t.Fatalf("got %v, want %s", got, want)
+		}
+		conn.reply(frame)
+	}
+	waitFor(t, "replies", func() bool { return c.Status().Pending == 0 })
+	if n := outbox.Len(); n != 0 {
+		t.Errorf("outbox has %d messages, want 0", n)
+	}
+	c.Shutdown(context.Background())
+	conn.close()
+	wait()
+}
+
+func discardLogger() *log.Logger {
+	return log.New(io.Discard, "", 0)
+}
+
+// logWriter is a ResponseWriter that discards writes and logs to log.
+type logWriter struct {
+	log *log.Logger
+}
+
+func (w logWriter) Write(map[string]interface{}) (int, error)     { return 0, nil }
+func (w logWriter) WriteMsg(types.ChannelID, string) (int, error) { return 0, nil }
+func (w logWriter) WriteTyping(types.ChannelID) (int, error)      { return 0, nil }
+func (w logWriter) ErrorLog() *log.Logger                         { return w.log }
+
+func TestTypedHandlerLogsToErrorLog(t *testing.T) {
+	var buf strings.Builder
+	mux := NewServeMux()
+	called := false
+	mux.HandleMessageFunc(func(ResponseWriter, *MessageEvent) { called = true })
+	mux.HandleEvent(logWriter{log.New(&buf, "", 0)}, &Envelope{Type: EventMessage, Raw: json.RawMessage(`{"type":"message","text":1}`)})
+	if called {
+		t.Error("handler called with an undecodable event")
+	}
+	if !strings.Contains(buf.String(), "failed to decode message event") {
+		t.Errorf("error log = %q, want the decode failure", buf.String())
+	}
+}
+
+// failingHistory is a HistoryFetcher that always fails.
+type failingHistory struct{}
+
+func (failingHistory) ConversationHistory(types.ChannelID, api.HistoryParams) (*api.HistoryResponse, error) {
+	return nil, errors.New("history unavailable")
+}
+
+func TestReactionMuxLogsToErrorLog(t *testing.T) {
+	var buf strings.Builder
+	w := logWriter{log.New(&buf, "", 0)}
+	mux := NewReactionMux(failingHistory{})
+	var got *Reaction
+	mux.HandleFunc("eyes", "", func(_ ResponseWriter, r *Reaction) { got = r })
+
+	mux.HandleEvent(w, &Envelope{Type: EventReactionAdded, Raw: json.RawMessage(`{"type":"reaction_added","reaction":1}`)})
+	if !strings.Contains(buf.String(), "failed to decode event") {
+		t.Errorf("error log = %q, want the decode failure", buf.String())
+	}
+
+	buf.Reset()
+	mux.HandleEvent(w, &Envelope{Type: EventReactionAdded, Raw: json.RawMessage(
+		`{"type":"reaction_added","reaction":"eyes","item":{"type":"message","channel":"C1","ts":"1.2"}}`)})
+	if got == nil || got.Message != nil {
+		t.Errorf("handler got %+v, want a reaction without its message", got)
+	}
+	if !strings.Contains(buf.String(), "history unavailable") {
+		t.Errorf("error log = %q, want the history failure", buf.String())
+	}
+}
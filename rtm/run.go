@@ -0,0 +1,251 @@
+package rtm
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/websocket"
+	"golang.org/x/sync/errgroup"
+)
+
+// eventQueueSize is the number of received events that may wait for the
+// dispatcher before the reader stops reading.
+const eventQueueSize = 64
+
+// writeRequest is a frame queued for the writer goroutine.
+type writeRequest struct {
+	data []byte
+	err  chan error // receives the result of the write
+}
+
+// Run opens a connection to the Slack RTM server and handles incoming
+// events with the handler until the connection fails, ctx is done or
+// Shutdown is called.
+//
+// The connection is served by a reader, a dispatcher, a writer and a pinger
+// goroutine, so a slow handler doesn't stop frames being read, pings being
+// sent or replies being written. If any of them fails the others are torn
+// down and Run returns that error once they have all stopped, which
+// includes waiting for the handler that is running to return. Events that
+// were received but not yet handled are dropped.
+//
+// Cancelling ctx closes the connection straight away and Run returns the
+// context's error; use Shutdown to wait for in-flight work instead. Run
+// always returns a non-nil error. After Shutdown the returned error is
+// ErrClientClosed.
+func (c *Client) Run(ctx context.Context, handler Handler) error {
+	if c.shuttingDown() {
+		return ErrClientClosed
+	}
+	ws, err := c.dial()
+	if err != nil {
+		return err
+	}
+
+	writes := make(chan *writeRequest)
+	c.mu.Lock()
+	// Shutdown may have run during the dial and found nothing to close.
+	if c.shuttingDown() {
+		c.mu.Unlock()
+		ws.Close()
+		return ErrClientClosed
+	}
+	c.ws = ws
+	c.ready = false
+	c.pending = make(map[int64][]byte)
+	c.writes = writes
+	c.done = make(chan struct{})
+	c.connections++
+	c.connectedAt = time.Now()
+	c.mu.Unlock()
+	defer c.closeConn()
+
+	events := make(chan *Envelope, eventQueueSize)
+	g, gctx := errgroup.WithContext(ctx)
+	g.Go(func() error { return c.reader(gctx, ws, events) })
+	g.Go(func() error { return c.dispatcher(gctx, handler, events) })
+	g.Go(func() error { return c.writer(gctx, ws, writes) })
+	g.Go(func() error { return c.pinger(gctx) })
+	g.Go(func() error {
+		// Closing the connection unblocks the reader and writer and
+		// fails queued writes once anything has gone wrong.
+		<-gctx.Done()
+		c.closeConn()
+		return nil
+	})
+	err = g.Wait()
+
+	for len(events) > 0 {
+		<-events
+		atomic.AddInt64(&c.inFlight, -1)
+	}
+	if c.shuttingDown() {
+		return ErrClientClosed
+	}
+	if err == nil {
+		err = ctx.Err()
+	}
+	return err
+}
+
+// reader reads frames from the connection, handles replies and queues
+// events for the dispatcher.
+func (c *Client) reader(ctx context.Context, ws *websocket.Conn, events chan<- *Envelope) error {
+	c.debug.Println("rtm.start ready to read event")
+	for {
+		var msg []byte
+		err := websocket.Message.Receive(ws, &msg)
+		if err != nil {
+			if c.shuttingDown() {
+				return ErrClientClosed
+			}
+			if ctx.Err() != nil {
+				// The connection was closed because another
+				// goroutine failed.
+				return nil
+			}
+			c.logger.Println("rtm.start ######### ws read failed", err)
+			return err
+		}
+		atomic.StoreInt64(&c.lastEvent, time.Now().UnixNano())
+		c.tapFrame(Inbound, nil, msg)
+		event, err := NewEnvelope(msg)
+		if err != nil {
+			// packet no good, we ignore it for now
+			c.logger.Println("rtm.start ###### error parsing event", string(msg), err)
+			continue
+		}
+		event.Transport = TransportRTM
+		c.ack(event)
+		if event.Type == EventHello {
+			c.mu.Lock()
+			c.ready = true
+			c.mu.Unlock()
+			c.flushOutbox()
+		}
+		if c.shuttingDown() {
+			// Keep reading so replies to pending messages are received
+			// but stop handing new events to the handler.
+			c.debug.Println("rtm.start dropping event during shutdown", string(msg))
+			continue
+		}
+		c.debug.Println("rtm.start handling event", string(msg))
+		atomic.AddInt64(&c.inFlight, 1)
+		select {
+		case events <- event:
+		case <-ctx.Done():
+			atomic.AddInt64(&c.inFlight, -1)
+			return nil
+		}
+	}
+}
+
+// dispatcher hands queued events to the handler one at a time, in the
+// order they were received.
+func (c *Client) dispatcher(ctx context.Context, handler Handler, events <-chan *Envelope) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event := <-events:
+			c.autoJoinEvent(event)
+			c.dispatch(handler, event)
+		}
+	}
+}
+
+// writer writes queued frames to the connection.
+func (c *Client) writer(ctx context.Context, ws *websocket.Conn, writes <-chan *writeRequest) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case req := <-writes:
+			if c.writeTimeout > 0 {
+				ws.SetWriteDeadline(time.Now().Add(c.writeTimeout))
+			}
+			_, err := ws.Write(req.data)
+			req.err <- err
+			if err != nil {
+				if c.shuttingDown() {
+					return ErrClientClosed
+				}
+				if ctx.Err() != nil {
+					return nil
+				}
+				c.logger.Println("rtm: ws write failed", err)
+				return err
+			}
+		}
+	}
+}
+
+// queueWrite hands data to the writer and waits for it to be written.
+// ErrNotConnected (or ErrClientClosed) is returned if the connection
+// closes first.
+func (c *Client) queueWrite(writes chan<- *writeRequest, done <-chan struct{}, data []byte) error {
+	req := &writeRequest{data: data, err: make(chan error, 1)}
+	select {
+	case writes <- req:
+	case <-done:
+		return c.notConnected()
+	}
+	select {
+	case err := <-req.err:
+		return err
+	case <-done:
+		return c.notConnected()
+	}
+}
+
+// pinger pings the server whenever nothing has been read for the ping
+// interval and fails with ErrPongTimeout if nothing is read within the
+// pong timeout of the ping.
+func (c *Client) pinger(ctx context.Context) error {
+	if c.pingInterval <= 0 {
+		<-ctx.Done()
+		return nil
+	}
+	start := time.Now()
+	lastRead := func() time.Time {
+		if last := atomic.LoadInt64(&c.lastEvent); last > start.UnixNano() {
+			return time.Unix(0, last)
+		}
+		return start
+	}
+
+	timer := time.NewTimer(c.pingInterval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-timer.C:
+		}
+		if quiet := time.Since(lastRead()); quiet < c.pingInterval {
+			timer.Reset(c.pingInterval - quiet)
+			continue
+		}
+		sent := time.Now()
+		if c.pongTimeout <= 0 {
+			c.ping()
+			timer.Reset(c.pingInterval)
+			continue
+		}
+		// Arm the deadline before writing so a slow write counts against
+		// it and a reply arriving during the write is still seen.
+		timer.Reset(c.pongTimeout)
+		c.ping()
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-timer.C:
+		}
+		if lastRead().Before(sent) {
+			c.logger.Println("rtm.start no pong received within", c.pongTimeout)
+			return ErrPongTimeout
+		}
+		timer.Reset(c.pingInterval - time.Since(lastRead()))
+	}
+}
@@ -0,0 +1,182 @@
+package rtm
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// livenessGrace is added to the ping interval and pong timeout to allow for
+// a slow read or handler before LivenessHandler reports the client as stuck.
+const livenessGrace = 10 * time.Second
+
+// Status is a snapshot of the state of a Client's connection.
+type Status struct {
+	// Connected is true while the connection is open and the server has
+	// said hello.
+	Connected bool
+	// ConnectedSince is when the current connection was opened, zero if
+	// there is none.
+	ConnectedSince time.Time
+	// LastEvent is when the last frame was read from the server, zero if
+	// none has been.
+	LastEvent time.Time
+	// Reconnects counts the connections opened after the first.
+	Reconnects int
+	// SendQueueDepth is the number of writes queued or in progress plus
+	// the messages waiting in the outbox, if any.
+	SendQueueDepth int
+	// Pending is the number of sent messages the server hasn't replied to.
+	Pending int
+	// PingLatency is the round trip time of the last answered ping, zero
+	// if none has been answered.
+	PingLatency time.Duration
+	// ShuttingDown is true once Shutdown has been called.
+	ShuttingDown bool
+	// HandlerSince is when the handler that is running was called, zero
+	// if no handler is running.
+	HandlerSince time.Time
+}
+
+// statusJSON is the JSON encoding of a Status.
+type statusJSON struct {
+	Connected      bool       `json:"connected"`
+	ConnectedSince *time.Time `json:"connected_since,omitempty"`
+	LastEvent      *time.Time `json:"last_event,omitempty"`
+	Reconnects     int        `json:"reconnects"`
+	SendQueueDepth int        `json:"send_queue_depth"`
+	Pending        int        `json:"pending"`
+	PingLatencyMS  float64    `json:"ping_latency_ms"`
+	ShuttingDown   bool       `json:"shutting_down"`
+	HandlerSince   *time.Time `json:"handler_since,omitempty"`
+}
+
+// MarshalJSON encodes the status for health check responses. Times that
+// are zero are left out and the ping latency is given in milliseconds.
+func (s Status) MarshalJSON() ([]byte, error) {
+	j := statusJSON{
+		Connected:      s.Connected,
+		ConnectedSince: optionalTime(s.ConnectedSince),
+		LastEvent:      optionalTime(s.LastEvent),
+		Reconnects:     s.Reconnects,
+		SendQueueDepth: s.SendQueueDepth,
+		Pending:        s.Pending,
+		PingLatencyMS:  float64(s.PingLatency) / float64(time.Millisecond),
+		ShuttingDown:   s.ShuttingDown,
+		HandlerSince:   optionalTime(s.HandlerSince),
+	}
+	return json.Marshal(&j)
+}
+
+// optionalTime returns nil for the zero time so it is omitted from JSON.
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// Status reports the current state of the client's connection. It is safe
+// to call from any goroutine.
+func (c *Client) Status() Status {
+	s := Status{
+		SendQueueDepth: int(atomic.LoadInt64(&c.writing)),
+		ShuttingDown:   c.shuttingDown(),
+	}
+	if last := atomic.LoadInt64(&c.lastEvent); last != 0 {
+		s.LastEvent = time.Unix(0, last)
+	}
+	if start := atomic.LoadInt64(&c.handlerStart); start != 0 {
+		s.HandlerSince = time.Unix(0, start)
+	}
+	if n, ok := c.outbox.(interface{ Len() int }); ok {
+		s.SendQueueDepth += n.Len()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s.Connected = c.ws != nil && c.ready
+	if c.ws != nil {
+		s.ConnectedSince = c.connectedAt
+	}
+	if c.connections > 1 {
+		s.Reconnects = c.connections - 1
+	}
+	s.Pending = len(c.pending)
+	s.PingLatency = c.pingLatency
+	return s
+}
+
+// ping sends a ping to the server, recording when it was sent so the
+// latency can be measured when the reply arrives.
+func (c *Client) ping() {
+	msg := map[string]interface{}{"type": EventPing}
+	sent := time.Now()
+	_, err := c.send(msg, nil, false)
+	if err != nil {
+		return
+	}
+	id, _ := msg["id"].(int64)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// The reply may already have been handled, in which case this ping
+	// isn't measured.
+	if _, waiting := c.pending[id]; waiting {
+		c.pingID = id
+		c.pingSent = sent
+	}
+}
+
+// ReadinessHandler returns an http.Handler reporting whether the client is
+// ready to handle events, for use as a Kubernetes readiness probe. It
+// responds 200 OK while the client is connected and 503 Service Unavailable
+// otherwise, with the client's Status as a JSON body.
+func ReadinessHandler(c *Client) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := c.Status()
+		writeStatus(w, s, s.Connected && !s.ShuttingDown)
+	})
+}
+
+// LivenessHandler returns an http.Handler reporting whether the client is
+// still working, for use as a Kubernetes liveness probe. Being disconnected
+// is not a failure since the client is expected to reconnect, but it responds
+// 503 Service Unavailable once the client has shut down, when a handler has
+// been running for longer than the ping interval and pong timeout allow, or
+// when a connection is open and nothing has been read from it for that long.
+// A blocked handler is caught even after the connection has been torn down
+// because of it, since Run can't return until the handler does. Otherwise it
+// responds 200 OK. The body is the client's Status as JSON.
+func LivenessHandler(c *Client) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := c.Status()
+		live := !s.ShuttingDown
+		stuck := c.pingInterval + c.pongTimeout + livenessGrace
+		if !s.HandlerSince.IsZero() && time.Since(s.HandlerSince) > stuck {
+			live = false
+		}
+		if !s.ConnectedSince.IsZero() {
+			since := s.LastEvent
+			if since.Before(s.ConnectedSince) {
+				since = s.ConnectedSince
+			}
+			if time.Since(since) > stuck {
+				live = false
+			}
+		}
+		writeStatus(w, s, live)
+	})
+}
+
+// writeStatus writes s as the JSON body of a health check response.
+func writeStatus(w http.ResponseWriter, s Status, ok bool) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if ok {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(&s)
+}
@@ -0,0 +1,61 @@
+package rtm
+
+import (
+	"runtime"
+	"time"
+)
+
+// Direction identifies whether a Frame was received or sent.
+type Direction int
+
+const (
+	// Inbound frames were received from the RTM server.
+	Inbound Direction = iota
+	// Outbound frames were sent to the RTM server.
+	Outbound
+)
+
+// String returns "inbound" or "outbound".
+func (d Direction) String() string {
+	if d == Outbound {
+		return "outbound"
+	}
+	return "inbound"
+}
+
+// Frame is a single websocket frame passed to a TapFunc.
+type Frame struct {
+	// Direction the frame travelled.
+	Direction Direction
+	// Time the frame was received or sent.
+	Time time.Time
+	// Event is the outbound message before it was encoded as JSON. Event is
+	// nil for inbound frames.
+	Event map[string]interface{}
+	// Data is the raw frame exactly as it was received or sent.
+	Data []byte
+}
+
+// TapFunc receives every raw frame that passes through a Client,
+// independent of the registered handlers. Inbound frames are tapped before
+// they are parsed so frames that aren't valid JSON are seen too. Tap
+// functions are called synchronously from the reading and writing
+// goroutines so they should hand off slow work (archiving, analytics) to
+// another goroutine. Tap functions must not modify the frame.
+type TapFunc func(f Frame)
+
+// tapFrame passes the frame to the client's tap, if any. Panics in the tap are
+// recovered and logged so a faulty tap can't take down the connection.
+func (c *Client) tapFrame(dir Direction, event map[string]interface{}, data []byte) {
+	if c.tap == nil {
+		return
+	}
+	defer func() {
+		if err := recover(); err != nil {
+			buf := make([]byte, 64<<10)
+			buf = buf[:runtime.Stack(buf, false)]
+			c.logger.Printf("rtm: panic in tap: %v\n%s", err, buf)
+		}
+	}()
+	c.tap(Frame{Direction: dir, Time: time.Now(), Event: event, Data: data})
+}
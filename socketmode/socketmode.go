@@ -0,0 +1,290 @@
+// Package socketmode implements a Slack Socket Mode client, which receives
+// Events API events, slash commands and interactions over a websocket
+// instead of a public HTTP endpoint.
+//
+// Events are passed to an rtm.Handler in the same Envelopes the rtm client
+// and the events receiver use, so handlers registered on an rtm.ServeMux
+// work unchanged.
+package socketmode
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gopackage/slack/api"
+	"github.com/gopackage/slack/rtm"
+	"golang.org/x/net/websocket"
+)
+
+// Envelope types sent by the Socket Mode server. Slash commands and
+// interactions are passed to the handler as events of these types with
+// the payload as the raw event.
+const (
+	EventHello         = "hello"
+	EventDisconnect    = "disconnect"
+	EventEventsAPI     = "events_api"
+	EventSlashCommands = "slash_commands"
+	EventInteractive   = "interactive"
+)
+
+// DefaultOrigin is the websocket origin used when none is set.
+const DefaultOrigin = "https://api.slack.com"
+
+// DefaultTimeout bounds opening a connection.
+const DefaultTimeout = 30 * time.Second
+
+// shutdownPollInterval is how often Shutdown checks whether the Client
+// has finished handling events.
+const shutdownPollInterval = 50 * time.Millisecond
+
+// ErrClientClosed is returned by the Client's DialAndListen method after a
+// call to Shutdown.
+var ErrClientClosed = errors.New("socketmode: Client closed")
+
+// DisconnectError is returned by the Client's DialAndListen method when
+// the server asks the client to reconnect, which it does routinely every
+// few hours.
+type DisconnectError struct {
+	// Reason is the reason given e.g. "refresh_requested" or "warning"
+	Reason string
+}
+
+// Error implements the error interface.
+func (e *DisconnectError) Error() string {
+	return fmt.Sprintf("socketmode: server requested disconnect: %s", e.Reason)
+}
+
+// envelope is a frame received from the Socket Mode server.
+type envelope struct {
+	Type       string          `json:"type"`
+	EnvelopeID string          `json:"envelope_id"`
+	Payload    json.RawMessage `json:"payload"`
+	Reason     string          `json:"reason"`
+}
+
+// Client is a Slack Socket Mode client.
+//
+// Clients contain state information so they should be created with
+// NewClient instead of reused.
+type Client struct {
+	apps       *api.Client
+	httpClient *http.Client
+	writer     *rtm.APIWriter
+	origin     string
+	timeout    time.Duration
+	logger     *log.Logger
+
+	inShutdown int32 // accessed atomically (non-zero means we're in Shutdown)
+	inFlight   int64 // accessed atomically, handlers currently running
+
+	mu sync.Mutex // guards ws
+	ws *websocket.Conn
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// NewClient creates a client that connects with the app-level token
+// (xapp-...). Handlers reply by posting with client, which should use the
+// app's bot token.
+func NewClient(appToken string, client *api.Client, opts ...Option) *Client {
+	c := &Client{
+		writer:  rtm.NewAPIWriter(client),
+		origin:  DefaultOrigin,
+		timeout: DefaultTimeout,
+		logger:  log.New(os.Stderr, "", log.LstdFlags),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.writer.Logger = c.logger
+	var apps []api.Option
+	if c.httpClient != nil {
+		apps = append(apps, api.WithHTTPClient(c.httpClient))
+	}
+	c.apps = api.NewClient(appToken, apps...)
+	return c
+}
+
+// WithLogger sets the logger the client reports connection problems,
+// handler panics and handler errors to.
+func WithLogger(logger *log.Logger) Option {
+	return func(c *Client) {
+		c.logger = logger
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to ask Slack for a connection
+// URL. The default is the Web API client's.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = client
+	}
+}
+
+// WithTimeout bounds opening a connection.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = d
+	}
+}
+
+// DialAndListen opens a Socket Mode connection and passes incoming events
+// to the handler until the connection closes. Every envelope is
+// acknowledged before it is handled, except during Shutdown when envelopes
+// are left for Slack to redeliver. Events API events are unwrapped so
+// the handler sees the same event types as over RTM; the server's hello
+// is passed on as an rtm.EventHello event and slash commands and
+// interactions as EventSlashCommands and EventInteractive events.
+//
+// DialAndListen always returns a non-nil error. After Shutdown the
+// returned error is ErrClientClosed; when the server asks the client to
+// reconnect it is a *DisconnectError.
+func (c *Client) DialAndListen(handler rtm.Handler) error {
+	if c.shuttingDown() {
+		return ErrClientClosed
+	}
+	u, err := c.apps.OpenConnection()
+	if err != nil {
+		return err
+	}
+	config, err := websocket.NewConfig(u, c.origin)
+	if err != nil {
+		return err
+	}
+	config.Dialer = &net.Dialer{Timeout: c.timeout}
+	ws, err := websocket.DialConfig(config)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	// Shutdown may have run during the dial and found nothing to close.
+	if c.shuttingDown() {
+		c.mu.Unlock()
+		ws.Close()
+		return ErrClientClosed
+	}
+	c.ws = ws
+	c.mu.Unlock()
+	defer c.closeConn()
+
+	for {
+		var msg []byte
+		err = websocket.Message.Receive(ws, &msg)
+		if err != nil {
+			if c.shuttingDown() {
+				return ErrClientClosed
+			}
+			return err
+		}
+		var env envelope
+		err = json.Unmarshal(msg, &env)
+		if err != nil {
+			c.logger.Println("socketmode: invalid envelope", string(msg), err)
+			continue
+		}
+		if env.Type == EventDisconnect {
+			return &DisconnectError{Reason: env.Reason}
+		}
+		if c.shuttingDown() {
+			// Leave the envelope unacknowledged so Slack redelivers it,
+			// to another connection if there is one.
+			continue
+		}
+		if env.EnvelopeID != "" {
+			err = c.ack(ws, env.EnvelopeID)
+			if err != nil {
+				return err
+			}
+		}
+		event, err := c.event(&env, msg)
+		if err != nil {
+			c.logger.Println("socketmode: invalid payload", string(msg), err)
+			continue
+		}
+		c.dispatch(handler, event)
+	}
+}
+
+// event converts an envelope into the event passed to the handler.
+func (c *Client) event(env *envelope, msg []byte) (*rtm.Envelope, error) {
+	switch env.Type {
+	case EventEventsAPI:
+		return rtm.NewCallbackEnvelope(env.Payload, rtm.TransportSocketMode)
+	case EventHello:
+		return &rtm.Envelope{Type: rtm.EventHello, Raw: msg, Transport: rtm.TransportSocketMode}, nil
+	}
+	return &rtm.Envelope{Type: env.Type, Raw: env.Payload, Transport: rtm.TransportSocketMode}, nil
+}
+
+// ack acknowledges an envelope so the server doesn't redeliver it.
+func (c *Client) ack(ws *websocket.Conn, id string) error {
+	data, err := json.Marshal(map[string]string{"envelope_id": id})
+	if err != nil {
+		return err
+	}
+	_, err = ws.Write(data)
+	return err
+}
+
+// dispatch passes the event to the handler, tracking it as in-flight until
+// the handler returns. Handler panics are recovered and logged.
+func (c *Client) dispatch(handler rtm.Handler, event *rtm.Envelope) {
+	atomic.AddInt64(&c.inFlight, 1)
+	defer atomic.AddInt64(&c.inFlight, -1)
+	defer func() {
+		if err := recover(); err != nil {
+			buf := make([]byte, 64<<10)
+			buf = buf[:runtime.Stack(buf, false)]
+			c.logger.Printf("socketmode: panic handling event %s: %v\n%s", event.Raw, err, buf)
+		}
+	}()
+	handler.HandleEvent(c.writer, event)
+}
+
+// Shutdown stops handing new events to the handler, waits for the running
+// handler to return and closes the connection. If the provided context
+// expires first the connection is closed anyway and Shutdown returns the
+// context's error. Once Shutdown has been called DialAndListen returns
+// ErrClientClosed.
+func (c *Client) Shutdown(ctx context.Context) error {
+	atomic.StoreInt32(&c.inShutdown, 1)
+
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
+	for atomic.LoadInt64(&c.inFlight) != 0 {
+		select {
+		case <-ctx.Done():
+			c.closeConn()
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	c.closeConn()
+	return nil
+}
+
+// shuttingDown returns true once Shutdown has been called.
+func (c *Client) shuttingDown() bool {
+	return atomic.LoadInt32(&c.inShutdown) != 0
+}
+
+// closeConn closes the active connection, if any.
+func (c *Client) closeConn() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ws != nil {
+		c.ws.Close()
+		c.ws = nil
+	}
+}
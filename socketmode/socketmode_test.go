@@ -0,0 +1,195 @@
+package socketmode
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gopackage/slack/api"
+	"github.com/gopackage/slack/rtm"
+	"golang.org/x/net/websocket"
+)
+
+// fakeSlack serves apps.connections.open and the Socket Mode websocket it
+// points to.
+type fakeSlack struct {
+	srv   *httptest.Server
+	conns chan *websocket.Conn
+	// onOpen, if set, is called when apps.connections.open is called.
+	onOpen func()
+	done   chan struct{}
+}
+
+func newFakeSlack(t *testing.T) *fakeSlack {
+	s := &fakeSlack{conns: make(chan *websocket.Conn, 1), done: make(chan struct{})}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/apps.connections.open", func(w http.ResponseWriter, r *http.Request) {
+		if s.onOpen != nil {
+			s.onOpen()
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "url": "ws://" + r.Host + "/link"})
+	})
+	mux.Handle("/link", websocket.Handler(func(ws *websocket.Conn) {
+		s.conns <- ws
+		<-s.done
+	}))
+	s.srv = httptest.NewServer(mux)
+	t.Cleanup(func() {
+		close(s.done)
+		s.srv.Close()
+	})
+	return s
+}
+
+func (s *fakeSlack) client() *Client {
+	u, _ := url.Parse(s.srv.URL)
+	return NewClient("xapp-test", api.NewClient("xoxb-test"),
+		WithHTTPClient(&http.Client{Transport: rewriteHost{u.Host}}),
+		WithLogger(log.New(io.Discard, "", 0)))
+}
+
+func (s *fakeSlack) accept(t *testing.T) *websocket.Conn {
+	select {
+	case ws := <-s.conns:
+		return ws
+	case <-time.After(5 * time.Second):
+		t.Fatal("client didn't connect")
+		return nil
+	}
+}
+
+// rewriteHost sends every request to host over plain HTTP.
+type rewriteHost struct{ host string }
+
+func (r rewriteHost) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = "http"
+	req.URL.Host = r.host
+	req.Host = r.host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// listen runs DialAndListen until the returned function is called, which
+// waits for it to return and reports its error.
+func listen(t *testing.T, c *Client, handler rtm.Handler) func() error {
+	errc := make(chan error, 1)
+	go func() { errc <- c.DialAndListen(handler) }()
+	return func() error {
+		select {
+		case err := <-errc:
+			return err
+		case <-time.After(5 * time.Second):
+			t.Fatal("DialAndListen didn't return")
+			return nil
+		}
+	}
+}
+
+func eventsAPI(id, text string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":        EventEventsAPI,
+		"envelope_id": id,
+		"payload": map[string]interface{}{
+			"type":  "event_callback",
+			"event": map[string]interface{}{"type": "message", "channel": "C1", "text": text},
+		},
+	}
+}
+
+// readAck reads the next acknowledgement written by the client.
+func readAck(t *testing.T, ws *websocket.Conn, timeout time.Duration) (string, bool) {
+	ws.SetReadDeadline(time.Now().Add(timeout))
+	var ack struct {
+		EnvelopeID string `json:"envelope_id"`
+	}
+	if err := websocket.JSON.Receive(ws, &ack); err != nil {
+		return "", false
+	}
+	return ack.EnvelopeID, true
+}
+
+func TestEventsAreAcknowledgedAndHandled(t *testing.T) {
+	s := newFakeSlack(t)
+	c := s.client()
+	events := make(chan *rtm.Envelope, 1)
+	wait := listen(t, c, rtm.HandlerFunc(func(w rtm.ResponseWriter, e *rtm.Envelope) {
+		events <- e
+	}))
+	ws := s.accept(t)
+	websocket.JSON.Send(ws, map[string]interface{}{"type": EventHello})
+	if e := <-events; e.Type != rtm.EventHello {
+		t.Fatalf("got %s event, want %s", e.Type, rtm.EventHello)
+	}
+	websocket.JSON.Send(ws, eventsAPI("e1", "hi"))
+	if id, ok := readAck(t, ws, 5*time.Second); !ok || id != "e1" {
+		t.Fatalf("ack = %q, %v, want e1", id, ok)
+	}
+	e := <-events
+	if e.Type != rtm.EventMessage || e.Transport != rtm.TransportSocketMode {
+		t.Errorf("got %s event over %v, want message over Socket Mode", e.Type, e.Transport)
+	}
+
+	websocket.JSON.Send(ws, map[string]interface{}{"type": EventDisconnect, "reason": "refresh_requested"})
+	err := wait()
+	if d, ok := err.(*DisconnectError); !ok || d.Reason != "refresh_requested" {
+		t.Errorf("DialAndListen = %v, want refresh_requested DisconnectError", err)
+	}
+}
+
+func TestShutdownLeavesNewEnvelopesUnacknowledged(t *testing.T) {
+	s := newFakeSlack(t)
+	c := s.client()
+	handling, release := make(chan struct{}), make(chan struct{})
+	wait := listen(t, c, rtm.HandlerFunc(func(w rtm.ResponseWriter, e *rtm.Envelope) {
+		close(handling)
+		<-release
+	}))
+	ws := s.accept(t)
+	websocket.JSON.Send(ws, eventsAPI("e1", "one"))
+	if id, _ := readAck(t, ws, 5*time.Second); id != "e1" {
+		t.Fatalf("ack = %q, want e1", id)
+	}
+	<-handling
+
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- c.Shutdown(context.Background()) }()
+	for !c.shuttingDown() {
+		time.Sleep(time.Millisecond)
+	}
+	websocket.JSON.Send(ws, eventsAPI("e2", "two"))
+	if id, ok := readAck(t, ws, 100*time.Millisecond); ok {
+		t.Errorf("envelope %s acknowledged during shutdown", id)
+	}
+	close(release)
+	if err := <-shutdown; err != nil {
+		t.Errorf("Shutdown = %v", err)
+	}
+	if err := wait(); err != ErrClientClosed {
+		t.Errorf("DialAndListen = %v, want %v", err, ErrClientClosed)
+	}
+}
+
+func TestShutdownDuringDial(t *testing.T) {
+	s := newFakeSlack(t)
+	opened, proceed := make(chan struct{}), make(chan struct{})
+	s.onOpen = func() {
+		close(opened)
+		<-proceed
+	}
+	c := s.client()
+	wait := listen(t, c, rtm.HandlerFunc(func(rtm.ResponseWriter, *rtm.Envelope) {}))
+	<-opened
+	if err := c.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	close(proceed)
+	if err := wait(); err != ErrClientClosed {
+		t.Errorf("DialAndListen = %v, want %v", err, ErrClientClosed)
+	}
+}
@@ -0,0 +1,79 @@
+package types
+
+// UserID identifies a user e.g. "U023BECGF". Users of Enterprise Grid
+// workspaces have IDs starting with "W".
+type UserID string
+
+// ChannelID identifies a conversation: a public channel ("C"), a private
+// channel or multi-person IM ("G") or a direct message ("D").
+type ChannelID string
+
+// TeamID identifies a team (workspace) e.g. "T024BE7LD". Enterprise Grid
+// organizations have IDs starting with "E".
+type TeamID string
+
+// BotID identifies a bot integration e.g. "B024BE7LB". Bots also have a
+// UserID which is used to mention them and to identify their messages.
+type BotID string
+
+// ID is a Slack ID of unknown kind, such as one extracted from message
+// text, that can be inspected to see what it identifies.
+type ID string
+
+// IsUser returns true if the ID identifies a user.
+func (id ID) IsUser() bool {
+	return hasPrefix(string(id), 'U', 'W')
+}
+
+// IsChannel returns true if the ID identifies any kind of conversation.
+func (id ID) IsChannel() bool {
+	return hasPrefix(string(id), 'C', 'G', 'D')
+}
+
+// IsBot returns true if the ID identifies a bot integration.
+func (id ID) IsBot() bool {
+	return hasPrefix(string(id), 'B')
+}
+
+// IsTeam returns true if the ID identifies a team or organization.
+func (id ID) IsTeam() bool {
+	return hasPrefix(string(id), 'T', 'E')
+}
+
+// IsUser returns true if the ID has a user prefix.
+func (id UserID) IsUser() bool {
+	return ID(id).IsUser()
+}
+
+// IsChannel returns true if the ID has a conversation prefix.
+func (id ChannelID) IsChannel() bool {
+	return ID(id).IsChannel()
+}
+
+// IsIM returns true if the ID identifies a direct message.
+func (id ChannelID) IsIM() bool {
+	return hasPrefix(string(id), 'D')
+}
+
+// IsTeam returns true if the ID has a team or organization prefix.
+func (id TeamID) IsTeam() bool {
+	return ID(id).IsTeam()
+}
+
+// IsBot returns true if the ID has a bot prefix.
+func (id BotID) IsBot() bool {
+	return ID(id).IsBot()
+}
+
+// hasPrefix returns true if id starts with one of the prefixes.
+func hasPrefix(id string, prefixes ...byte) bool {
+	if len(id) < 2 {
+		return false
+	}
+	for _, p := range prefixes {
+		if id[0] == p {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,133 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Timestamp is a Slack timestamp such as "1403051575.000407": seconds since
+// the Unix epoch with a microsecond fraction. Message timestamps are unique
+// within a channel so Slack uses them as message IDs. Timestamp keeps the
+// exact string Slack sent so it can be passed back to the API unchanged.
+type Timestamp string
+
+// NewTimestamp creates a Timestamp for the time t with microsecond precision.
+func NewTimestamp(t time.Time) Timestamp {
+	return Timestamp(fmt.Sprintf("%d.%06d", t.Unix(), t.Nanosecond()/int(time.Microsecond)))
+}
+
+// UnmarshalJSON decodes a timestamp sent as either a JSON string or a number.
+func (ts *Timestamp) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		err := json.Unmarshal(data, &s)
+		if err != nil {
+			return err
+		}
+		*ts = Timestamp(s)
+		return nil
+	}
+	if string(data) == "null" {
+		*ts = ""
+		return nil
+	}
+	var n json.Number
+	err := json.Unmarshal(data, &n)
+	if err != nil {
+		return fmt.Errorf("types: invalid timestamp %s", data)
+	}
+	*ts = Timestamp(n.String())
+	return nil
+}
+
+// IsZero returns true if the timestamp is empty.
+func (ts Timestamp) IsZero() bool {
+	return ts == ""
+}
+
+// String returns the timestamp as sent by Slack.
+func (ts Timestamp) String() string {
+	return string(ts)
+}
+
+// UnixMicro returns the timestamp as microseconds since the Unix epoch.
+// Invalid timestamps return zero.
+func (ts Timestamp) UnixMicro() int64 {
+	sec, frac := ts.split()
+	s, err := strconv.ParseInt(sec, 10, 64)
+	if err != nil {
+		return 0
+	}
+	if len(frac) > 6 {
+		frac = frac[:6]
+	}
+	frac += strings.Repeat("0", 6-len(frac))
+	us, err := strconv.ParseInt(frac, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return s*int64(time.Second/time.Microsecond) + us
+}
+
+// Time returns the timestamp as a time.Time. Empty timestamps return the
+// zero time.
+func (ts Timestamp) Time() time.Time {
+	if ts.IsZero() {
+		return time.Time{}
+	}
+	us := ts.UnixMicro()
+	return time.Unix(us/1e6, (us%1e6)*int64(time.Microsecond))
+}
+
+// Compare returns -1, 0 or 1 if ts is before, the same as or after other.
+// Empty timestamps are before all others. Timestamps are compared as
+// decimal strings so no precision is lost.
+func (ts Timestamp) Compare(other Timestamp) int {
+	if ts.IsZero() || other.IsZero() {
+		return sign(len(ts) - len(other))
+	}
+	as, af := ts.split()
+	bs, bf := other.split()
+	as, bs = strings.TrimLeft(as, "0"), strings.TrimLeft(bs, "0")
+	switch {
+	case len(as) != len(bs):
+		return sign(len(as) - len(bs))
+	case as != bs:
+		return strings.Compare(as, bs)
+	}
+	return strings.Compare(strings.TrimRight(af, "0"), strings.TrimRight(bf, "0"))
+}
+
+// Before returns true if ts is earlier than other.
+func (ts Timestamp) Before(other Timestamp) bool {
+	return ts.Compare(other) < 0
+}
+
+// After returns true if ts is later than other.
+func (ts Timestamp) After(other Timestamp) bool {
+	return ts.Compare(other) > 0
+}
+
+// split splits the timestamp into its seconds and fractional parts.
+func (ts Timestamp) split() (string, string) {
+	s := string(ts)
+	i := strings.IndexByte(s, '.')
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], s[i+1:]
+}
+
+// sign returns -1, 0 or 1 matching the sign of n.
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
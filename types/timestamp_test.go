@@ -0,0 +1,95 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestTimestampUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		json    string
+		want    Timestamp
+		wantErr bool
+	}{
+		{json: `"1403051575.000407"`, want: "1403051575.000407"},
+		{json: `1403051575.000407`, want: "1403051575.000407"},
+		{json: `1403051575`, want: "1403051575"},
+		{json: `""`, want: ""},
+		{json: `null`, want: ""},
+		{json: `true`, wantErr: true},
+		{json: `{}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		var ts Timestamp
+		err := json.Unmarshal([]byte(tt.json), &ts)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Unmarshal(%s) error = %v, wantErr %v", tt.json, err, tt.wantErr)
+			continue
+		}
+		if ts != tt.want {
+			t.Errorf("Unmarshal(%s) = %q, want %q", tt.json, ts, tt.want)
+		}
+	}
+}
+
+func TestTimestampUnixMicro(t *testing.T) {
+	tests := []struct {
+		ts   Timestamp
+		want int64
+	}{
+		{"1403051575.000407", 1403051575000407},
+		{"1403051575.5", 1403051575500000},
+		{"1403051575.1234567", 1403051575123456},
+		{"1403051575", 1403051575000000},
+		{"", 0},
+		{"abc.000407", 0},
+		{"1403051575.x", 0},
+	}
+	for _, tt := range tests {
+		if got := tt.ts.UnixMicro(); got != tt.want {
+			t.Errorf("%q.UnixMicro() = %d, want %d", tt.ts, got, tt.want)
+		}
+	}
+}
+
+func TestTimestampTime(t *testing.T) {
+	want := time.Unix(1403051575, 407000)
+	if got := Timestamp("1403051575.000407").Time(); !got.Equal(want) {
+		t.Errorf("Time() = %v, want %v", got, want)
+	}
+	if got := Timestamp("").Time(); !got.IsZero() {
+		t.Errorf("empty Time() = %v, want zero", got)
+	}
+	if got := NewTimestamp(want); got != "1403051575.000407" {
+		t.Errorf("NewTimestamp = %q, want %q", got, "1403051575.000407")
+	}
+}
+
+func TestTimestampCompare(t *testing.T) {
+	tests := []struct {
+		a, b Timestamp
+		want int
+	}{
+		{"1403051575.000407", "1403051575.000407", 0},
+		{"1403051575.000407", "1403051575.000408", -1},
+		{"1403051576.000001", "1403051575.999999", 1},
+		{"999999999.000000", "1403051575.000000", -1},
+		{"1403051575.5", "1403051575.500000", 0},
+		{"01403051575.1", "1403051575.1", 0},
+		{"", "1403051575.000407", -1},
+		{"1403051575.000407", "", 1},
+		{"", "", 0},
+	}
+	for _, tt := range tests {
+		if got := tt.a.Compare(tt.b); got != tt.want {
+			t.Errorf("%q.Compare(%q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := tt.a.Before(tt.b); got != (tt.want < 0) {
+			t.Errorf("%q.Before(%q) = %v", tt.a, tt.b, got)
+		}
+		if got := tt.a.After(tt.b); got != (tt.want > 0) {
+			t.Errorf("%q.After(%q) = %v", tt.a, tt.b, got)
+		}
+	}
+}
@@ -3,15 +3,17 @@ package types
 // Channel contains information about a team channel.
 type Channel struct {
 	// ID is the uuid for this channel
-	ID string `json:"id"`
+	ID ChannelID `json:"id"`
 	// Name of the the channel without leading hash sign.
 	Name string `json:"name"`
 	// IsChannel is true if the object is a channel (always set for channels)
 	IsChannel bool `json:"is_channel"`
+	// IsPrivate is true if the channel is private (only visible to members)
+	IsPrivate bool `json:"is_private,omitempty"`
 	// Created is the unix timestamp when the channel was created
 	Created int64 `json:"created"`
 	// Creator is the user ID of the creator of the channel
-	Creator string `json:"creator"`
+	Creator UserID `json:"creator"`
 	// IsArchived is true if the channel is archived
 	IsArchived bool `json:"is_archived"`
 	// IsGeneral is true if the channel is the "general" channel that includes
@@ -20,7 +22,7 @@ type Channel struct {
 	IsGeneral bool `json:"is_general"`
 	// Members is a list of user IDs for all uers in this channel. This includes
 	// any disabled accounts that were in this channel when they were disabled.
-	Members []string `json:"members"`
+	Members []UserID `json:"members"`
 	// Topic is optional current topic of discussion on the channel
 	Topic Property `json:"topic,omitempty"`
 	// Purpose is optional "mission statement" for the channel
@@ -29,7 +31,7 @@ type Channel struct {
 	IsMember bool `json:"is_member"`
 	// LastRead is an optional timestamp for the last message the calling
 	// member has read in this channel
-	LastRead string `json:"last_read,omitempty"`
+	LastRead Timestamp `json:"last_read,omitempty"`
 	// Latest is the last message posted to the channel
 	//Latest Message `json:"latest,omitempty"`
 
@@ -48,7 +50,58 @@ type Property struct {
 	// Value contains the property value.
 	Value string `json:"value"`
 	// Creator is the user ID of the creator of the property.
-	Creator string `json:"creator"`
+	Creator UserID `json:"creator"`
 	// LastSet is the unix timestamp when the property was last set.
 	LastSet int64 `json:"last_set"`
 }
+
+// Message is a message posted to a channel, group or IM.
+type Message struct {
+	// Type is always "message"
+	Type string `json:"type"`
+	// Subtype is set for messages that aren't plain user messages
+	// e.g. "bot_message", "channel_join", "message_changed"
+	Subtype string `json:"subtype,omitempty"`
+	// Channel is the ID of the channel the message was posted to. Channel
+	// is not set on messages returned from channel history.
+	Channel ChannelID `json:"channel,omitempty"`
+	// User is the user ID of the author of the message
+	User UserID `json:"user,omitempty"`
+	// BotID is the ID of the bot that posted the message, if any
+	BotID BotID `json:"bot_id,omitempty"`
+	// Text of the message
+	Text string `json:"text"`
+	// TS is the timestamp of the message which is unique within the channel
+	// e.g. "1403051575.000407"
+	TS Timestamp `json:"ts"`
+	// ThreadTS is the timestamp of the parent message if the message is
+	// part of a thread
+	ThreadTS Timestamp `json:"thread_ts,omitempty"`
+}
+
+// File contains information about a file shared in Slack.
+type File struct {
+	// ID is the uuid for this file e.g. "F2147483862"
+	ID string `json:"id"`
+	// Created is the unix timestamp when the file was uploaded
+	Created int64 `json:"created"`
+	// Name is the file name e.g. "report.csv"
+	Name string `json:"name"`
+	// Title is the display title of the file
+	Title string `json:"title"`
+	// Mimetype is the type of the file e.g. "text/csv"
+	Mimetype string `json:"mimetype"`
+	// Filetype is Slack's short name for the file type e.g. "csv"
+	Filetype string `json:"filetype"`
+	// User is the user ID of the user that uploaded the file
+	User UserID `json:"user"`
+	// Size of the file in bytes
+	Size int64 `json:"size"`
+	// URLPrivate points to the file contents. Fetching it requires a token.
+	URLPrivate string `json:"url_private"`
+	// URLPrivateDownload points to the file contents with headers that make
+	// browsers download it. Fetching it requires a token.
+	URLPrivateDownload string `json:"url_private_download,omitempty"`
+	// Permalink is the URL to the file's page in Slack
+	Permalink string `json:"permalink,omitempty"`
+}
@@ -0,0 +1,191 @@
+package webhook
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"runtime"
+
+	"github.com/gopackage/slack/types"
+)
+
+// ErrAlreadyResponded is returned when an outgoing webhook handler writes
+// more than one response.
+var ErrAlreadyResponded = errors.New("slack: outgoing webhook already responded")
+
+// OutgoingWebhook is a message posted by a legacy outgoing webhook
+// integration.
+type OutgoingWebhook struct {
+	// Token is the integration's verification token.
+	Token string
+	// TeamID is the ID of the team the message was posted in.
+	TeamID types.TeamID
+	// TeamDomain is the team's slack.com subdomain e.g. "acme".
+	TeamDomain string
+	// ChannelID is the ID of the channel the message was posted to.
+	ChannelID types.ChannelID
+	// ChannelName is the channel's name without leading hash sign.
+	ChannelName string
+	// Timestamp is the timestamp of the message.
+	Timestamp types.Timestamp
+	// UserID is the ID of the user that posted the message.
+	UserID types.UserID
+	// UserName is the name of the user that posted the message.
+	UserName string
+	// Text is the full text of the message, including the trigger word.
+	Text string
+	// TriggerWord is the configured trigger word that matched the start
+	// of the message, empty if the integration triggers on every message
+	// in its channel.
+	TriggerWord string
+	// ServiceID identifies the outgoing webhook integration.
+	ServiceID string
+}
+
+// OutgoingResponse is the message posted in reply to an outgoing webhook.
+type OutgoingResponse struct {
+	// Text is the message text.
+	Text string `json:"text"`
+	// Username overrides the integration's name.
+	Username string `json:"username,omitempty"`
+	// IconEmoji overrides the integration's icon with an emoji, e.g.
+	// ":robot_face:".
+	IconEmoji string `json:"icon_emoji,omitempty"`
+	// IconURL overrides the integration's icon with an image.
+	IconURL string `json:"icon_url,omitempty"`
+}
+
+// OutgoingResponseWriter is used by an OutgoingHandler to reply in the
+// channel the message came from. At most one reply can be written; if the
+// handler doesn't write one nothing is posted.
+type OutgoingResponseWriter interface {
+	// Write replies with resp.
+	Write(resp *OutgoingResponse) error
+	// WriteText replies with text.
+	WriteText(text string) error
+}
+
+// OutgoingHandler handles messages posted by outgoing webhooks. Slack
+// waits about three seconds for the reply, so slow work should be done
+// in a goroutine that posts its result through the Web API instead.
+type OutgoingHandler interface {
+	HandleOutgoing(w OutgoingResponseWriter, hook *OutgoingWebhook)
+}
+
+// OutgoingHandlerFunc is an adapter to allow the use of ordinary functions
+// as outgoing webhook handlers.
+type OutgoingHandlerFunc func(OutgoingResponseWriter, *OutgoingWebhook)
+
+// HandleOutgoing calls f(w, hook).
+func (f OutgoingHandlerFunc) HandleOutgoing(w OutgoingResponseWriter, hook *OutgoingWebhook) {
+	f(w, hook)
+}
+
+// OutgoingReceiver is an http.Handler that receives legacy outgoing
+// webhook POSTs, checks their verification token and passes them to an
+// OutgoingHandler.
+type OutgoingReceiver struct {
+	handler OutgoingHandler
+	tokens  [][]byte
+}
+
+// NewOutgoingReceiver creates a receiver passing webhooks to h. Only
+// requests carrying one of the tokens are accepted; several tokens can be
+// given when one endpoint serves integrations in several workspaces.
+func NewOutgoingReceiver(h OutgoingHandler, tokens ...string) *OutgoingReceiver {
+	if len(tokens) == 0 {
+		panic("webhook: NewOutgoingReceiver needs at least one token")
+	}
+	r := &OutgoingReceiver{handler: h}
+	for _, token := range tokens {
+		r.tokens = append(r.tokens, []byte(token))
+	}
+	return r
+}
+
+// ServeHTTP parses the webhook and passes it to the handler, writing the
+// handler's reply as the response. Requests that aren't POSTs or have no
+// valid token are rejected.
+func (r *OutgoingReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	err := req.ParseForm()
+	if err != nil {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+	hook := &OutgoingWebhook{
+		Token:       req.PostForm.Get("token"),
+		TeamID:      types.TeamID(req.PostForm.Get("team_id")),
+		TeamDomain:  req.PostForm.Get("team_domain"),
+		ChannelID:   types.ChannelID(req.PostForm.Get("channel_id")),
+		ChannelName: req.PostForm.Get("channel_name"),
+		Timestamp:   types.Timestamp(req.PostForm.Get("timestamp")),
+		UserID:      types.UserID(req.PostForm.Get("user_id")),
+		UserName:    req.PostForm.Get("user_name"),
+		Text:        req.PostForm.Get("text"),
+		TriggerWord: req.PostForm.Get("trigger_word"),
+		ServiceID:   req.PostForm.Get("service_id"),
+	}
+	if !r.valid(hook.Token) {
+		http.Error(w, "invalid token", http.StatusForbidden)
+		return
+	}
+
+	rw := &outgoingWriter{w: w}
+	defer func() {
+		if err := recover(); err != nil {
+			buf := make([]byte, 64<<10)
+			buf = buf[:runtime.Stack(buf, false)]
+			log.Printf("webhook: panic handling outgoing webhook %q: %v\n%s", hook.Text, err, buf)
+			if !rw.written {
+				http.Error(w, "internal error", http.StatusInternalServerError)
+			}
+		}
+	}()
+	r.handler.HandleOutgoing(rw, hook)
+	if !rw.written {
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// valid reports whether token is one of the receiver's tokens, taking
+// the same time whichever it matches.
+func (r *OutgoingReceiver) valid(token string) bool {
+	ok := 0
+	for _, t := range r.tokens {
+		ok |= subtle.ConstantTimeCompare([]byte(token), t)
+	}
+	return token != "" && ok == 1
+}
+
+// outgoingWriter writes the handler's reply as the JSON response body.
+type outgoingWriter struct {
+	w       http.ResponseWriter
+	written bool
+}
+
+// Write replies with resp.
+func (o *outgoingWriter) Write(resp *OutgoingResponse) error {
+	if o.written {
+		return ErrAlreadyResponded
+	}
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	o.written = true
+	o.w.Header().Set("Content-Type", "application/json")
+	_, err = o.w.Write(data)
+	return err
+}
+
+// WriteText replies with text.
+func (o *outgoingWriter) WriteText(text string) error {
+	return o.Write(&OutgoingResponse{Text: text})
+}
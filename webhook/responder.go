@@ -0,0 +1,197 @@
+// Package webhook implements the HTTP side of Slack integrations: replying
+// to slash commands and interactions through their response_url, and
+// receiving legacy outgoing webhooks.
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gopackage/slack/types"
+)
+
+// Limits Slack places on a response_url.
+const (
+	// ResponseURLLifetime is how long a response_url accepts messages
+	// after the command or interaction was received.
+	ResponseURLLifetime = 30 * time.Minute
+	// MaxResponses is the number of messages a response_url accepts.
+	MaxResponses = 5
+)
+
+// DefaultTimeout bounds each post made by a Responder without a Client.
+const DefaultTimeout = 30 * time.Second
+
+// defaultClient posts messages for Responders without a Client.
+var defaultClient = &http.Client{Timeout: DefaultTimeout}
+
+// Values for Message.ResponseType.
+const (
+	// ResponseEphemeral shows the message only to the user who invoked
+	// the command. It is Slack's default.
+	ResponseEphemeral = "ephemeral"
+	// ResponseInChannel shows the message to everyone in the channel.
+	ResponseInChannel = "in_channel"
+)
+
+// ExpiredError is returned by a Responder once its response_url is older
+// than ResponseURLLifetime.
+type ExpiredError struct {
+	// Expired is when the response_url stopped accepting messages.
+	Expired time.Time
+}
+
+// Error implements the error interface.
+func (e *ExpiredError) Error() string {
+	return fmt.Sprintf("slack: response_url expired at %s", e.Expired.Format(time.RFC3339))
+}
+
+// UsedUpError is returned by a Responder once its response_url has been
+// used MaxResponses times.
+type UsedUpError struct {
+	// Uses is the number of messages already sent to the response_url.
+	Uses int
+}
+
+// Error implements the error interface.
+func (e *UsedUpError) Error() string {
+	return fmt.Sprintf("slack: response_url already used %d times", e.Uses)
+}
+
+// Message is a message posted to a response_url.
+type Message struct {
+	// Text is the message text, which may use Slack's mrkdwn formatting.
+	Text string `json:"text,omitempty"`
+	// ResponseType is ResponseEphemeral or ResponseInChannel. Empty
+	// leaves the choice to Slack, which defaults to ephemeral.
+	ResponseType string `json:"response_type,omitempty"`
+	// ReplaceOriginal replaces the message the interaction came from
+	// instead of posting a new one.
+	ReplaceOriginal bool `json:"replace_original,omitempty"`
+	// DeleteOriginal deletes the message the interaction came from.
+	DeleteOriginal bool `json:"delete_original,omitempty"`
+	// ThreadTS posts the message as a reply in the thread.
+	ThreadTS types.Timestamp `json:"thread_ts,omitempty"`
+	// Blocks is a JSON array of Block Kit blocks.
+	Blocks json.RawMessage `json:"blocks,omitempty"`
+}
+
+// Responder posts follow-up messages to the response_url of a slash
+// command or interaction. Handlers have three seconds to acknowledge a
+// command, so slow work is usually done in a goroutine that responds
+// later through a Responder. Responders keep track of Slack's limits and
+// return an *ExpiredError or *UsedUpError instead of posting a message
+// Slack would reject. Responders are safe for concurrent use.
+type Responder struct {
+	// URL is the response_url from the command or interaction payload.
+	URL string
+	// Received is when the command or interaction was received, which
+	// starts the response_url's lifetime. Zero disables the check.
+	Received time.Time
+	// Client is the HTTP client used to post messages. Nil uses a client
+	// with a timeout of DefaultTimeout.
+	Client *http.Client
+
+	mu   sync.Mutex
+	uses int
+}
+
+// NewResponder creates a Responder for responseURL. It should be created
+// as soon as the command or interaction is received.
+func NewResponder(responseURL string) *Responder {
+	return &Responder{URL: responseURL, Received: time.Now()}
+}
+
+// Respond posts msg to the response_url.
+func (r *Responder) Respond(msg *Message) error {
+	err := r.use()
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	client := r.Client
+	if client == nil {
+		client = defaultClient
+	}
+	resp, err := client.Post(r.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		io.Copy(ioutil.Discard, resp.Body)
+		return nil
+	}
+	// Slack explains rejections in the body, either as plain text or as
+	// a JSON error, and reports the limits the same way.
+	reason, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	switch {
+	case bytes.Contains(reason, []byte("expired_url")):
+		return &ExpiredError{Expired: r.Received.Add(ResponseURLLifetime)}
+	case bytes.Contains(reason, []byte("used_url")):
+		return &UsedUpError{Uses: MaxResponses}
+	}
+	return fmt.Errorf("slack: response_url returned HTTP status %s: %s", resp.Status, strings.TrimSpace(string(reason)))
+}
+
+// Ephemeral responds with text shown only to the user who invoked the
+// command.
+func (r *Responder) Ephemeral(text string) error {
+	return r.Respond(&Message{Text: text, ResponseType: ResponseEphemeral})
+}
+
+// InChannel responds with text shown to everyone in the channel.
+func (r *Responder) InChannel(text string) error {
+	return r.Respond(&Message{Text: text, ResponseType: ResponseInChannel})
+}
+
+// Replace replaces the message the interaction came from with text.
+func (r *Responder) Replace(text string) error {
+	return r.Respond(&Message{Text: text, ReplaceOriginal: true})
+}
+
+// Delete deletes the message the interaction came from.
+func (r *Responder) Delete() error {
+	return r.Respond(&Message{DeleteOriginal: true})
+}
+
+// Remaining returns how many more messages may be sent and for how long.
+func (r *Responder) Remaining() (int, time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	left := ResponseURLLifetime
+	if !r.Received.IsZero() {
+		left = time.Until(r.Received.Add(ResponseURLLifetime))
+	}
+	if left < 0 {
+		left = 0
+	}
+	return MaxResponses - r.uses, left
+}
+
+// use counts a message against the response_url's limits, returning an
+// error if it can't be sent. Messages count whether or not Slack accepts
+// them since a failed post may still have been delivered.
+func (r *Responder) use() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	expired := r.Received.Add(ResponseURLLifetime)
+	if !r.Received.IsZero() && time.Now().After(expired) {
+		return &ExpiredError{Expired: expired}
+	}
+	if r.uses >= MaxResponses {
+		return &UsedUpError{Uses: r.uses}
+	}
+	r.uses++
+	return nil
+}
@@ -0,0 +1,78 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestResponderLimits(t *testing.T) {
+	var posted []Message
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var m Message
+		json.NewDecoder(r.Body).Decode(&m)
+		posted = append(posted, m)
+	}))
+	defer srv.Close()
+
+	r := NewResponder(srv.URL)
+	for i := 0; i < MaxResponses; i++ {
+		if err := r.InChannel("hello"); err != nil {
+			t.Fatalf("response %d: %v", i+1, err)
+		}
+	}
+	if _, ok := r.Ephemeral("one too many").(*UsedUpError); !ok {
+		t.Error("response after MaxResponses wasn't a *UsedUpError")
+	}
+	if len(posted) != MaxResponses || posted[0].Text != "hello" || posted[0].ResponseType != ResponseInChannel {
+		t.Errorf("server received %+v", posted)
+	}
+
+	r = &Responder{URL: srv.URL, Received: time.Now().Add(-ResponseURLLifetime - time.Second)}
+	if _, ok := r.Ephemeral("late").(*ExpiredError); !ok {
+		t.Error("response after ResponseURLLifetime wasn't an *ExpiredError")
+	}
+}
+
+func TestResponderErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		check  func(error) bool
+	}{
+		{"ok", http.StatusOK, "ok", func(err error) bool { return err == nil }},
+		{"expired", http.StatusNotFound, "expired_url", func(err error) bool { _, ok := err.(*ExpiredError); return ok }},
+		{"used", http.StatusNotFound, `{"ok":false,"error":"used_url"}`, func(err error) bool { _, ok := err.(*UsedUpError); return ok }},
+		{"other", http.StatusInternalServerError, "oops", func(err error) bool { return err != nil }},
+	}
+	for _, tt := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+			w.Write([]byte(tt.body))
+		}))
+		err := NewResponder(srv.URL).Replace("done")
+		if !tt.check(err) {
+			t.Errorf("%s: Respond = %v", tt.name, err)
+		}
+		srv.Close()
+	}
+}
+
+func TestResponderTimeout(t *testing.T) {
+	if defaultClient.Timeout <= 0 {
+		t.Fatal("the default client has no timeout")
+	}
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+	r := &Responder{URL: srv.URL, Client: &http.Client{Timeout: 50 * time.Millisecond}}
+	if err := r.Delete(); err == nil {
+		t.Error("Respond to a stalled response_url succeeded")
+	}
+}